@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/exporter"
+)
+
+func runExport(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	formatFlag := fs.String("format", string(exporter.FormatNDJSON), "output format: csv, ndjson or parquet")
+	shapeFlag := fs.String("shape", "", "flat (one row per item) or nested (one row per order); default nested for ndjson, flat otherwise")
+	output := fs.String("o", "-", "output file, - for stdout")
+	customerID := fs.String("customer-id", "", "only export orders of this customer")
+	deliveryService := fs.String("delivery-service", "", "only export orders shipped by this delivery service")
+	createdFrom := fs.String("created-from", "", "only export orders created at or after this RFC3339 time")
+	createdTo := fs.String("created-to", "", "only export orders created before this RFC3339 time")
+	limit := fs.Int("limit", 0, "maximum number of orders to export, 0 for all")
+	fs.Parse(args)
+
+	format, err := exporter.ParseFormat(*formatFlag)
+	if err != nil {
+		return err
+	}
+	shape := exporter.ShapeFlat
+	if format == exporter.FormatNDJSON {
+		shape = exporter.ShapeNested
+	}
+	if *shapeFlag != "" {
+		if shape, err = exporter.ParseShape(*shapeFlag); err != nil {
+			return err
+		}
+	}
+
+	filter := db.OrderFilter{CustomerID: *customerID, DeliveryService: *deliveryService, Limit: *limit}
+	if *createdFrom != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339, *createdFrom); err != nil {
+			return fmt.Errorf("invalid -created-from: %w", err)
+		}
+	}
+	if *createdTo != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339, *createdTo); err != nil {
+			return fmt.Errorf("invalid -created-to: %w", err)
+		}
+	}
+
+	var dst io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dst = f
+	}
+	buffered := bufio.NewWriterSize(dst, 256<<10)
+
+	dbConn, err := openDB(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	ew, err := exporter.NewWriter(buffered, format, shape)
+	if err != nil {
+		return err
+	}
+	count, err := exporter.Export(ew, func(fn func(db.OrderRecord) error) error {
+		return db.StreamOrders(ctx, dbConn, filter, 1000, fn)
+	})
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Exported %d orders as %s/%s\n", count, format, shape)
+	return nil
+}
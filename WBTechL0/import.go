@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/importer"
+)
+
+func runImport(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	batchSize := fs.Int("batch-size", importer.DefaultBatchSize, "number of orders inserted per transaction")
+	rejectsPath := fs.String("rejects", "", "file receiving rejected records as NDJSON (default: log them)")
+	checkpointPath := fs.String("checkpoint", "", "file storing the last committed record, used to resume an interrupted import")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: main import [flags] [file|-]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var src io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	opts := importer.Options{BatchSize: *batchSize, Checkpoint: *checkpointPath}
+	if *rejectsPath != "" {
+		rejects, err := os.OpenFile(*rejectsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer rejects.Close()
+		opts.Rejects = rejects
+	}
+
+	dbConn, err := openDB(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	report, err := importer.Run(src, func(records []db.OrderRecord) ([]error, error) {
+		return db.AddOrders(ctx, dbConn, records)
+	}, opts)
+	log.Printf("Import finished: read=%d imported=%d rejected=%d skipped=%d last_record=%d\n",
+		report.Read, report.Imported, report.Rejected, report.Skipped, report.LastRecord)
+	return err
+}
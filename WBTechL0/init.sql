@@ -8,7 +8,7 @@ CREATE TABLE orders (
                         delivery_service VARCHAR,
                         shardkey VARCHAR,
                         sm_id INTEGER,
-                        date_created TIMESTAMP,
+                        date_created TIMESTAMPTZ,
                         oof_shard VARCHAR
 );
 
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type APIKey struct {
+	Key     string   `json:"key"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+type APIKeyAuthenticator struct {
+	keys map[[sha256.Size]byte]*Principal
+}
+
+func NewAPIKeyAuthenticator(keys []APIKey) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{keys: make(map[[sha256.Size]byte]*Principal, len(keys))}
+	for _, k := range keys {
+		if k.Key == "" {
+			continue
+		}
+		a.keys[sha256.Sum256([]byte(k.Key))] = &Principal{Subject: k.Subject, Roles: parseRoles(k.Roles)}
+	}
+	return a
+}
+
+func LoadAPIKeys(path string) (*APIKeyAuthenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing API keys file %s: %w", path, err)
+	}
+	return NewAPIKeyAuthenticator(keys), nil
+}
+
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := APIKeyFromRequest(r)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	principal, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return principal, nil
+}
+
+func APIKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok {
+		return strings.TrimSpace(key)
+	}
+	return ""
+}
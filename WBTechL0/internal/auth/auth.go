@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type Role string
+
+const (
+	RoleSupport Role = "support"
+	RoleFinance Role = "finance"
+	RoleAdmin   Role = "admin"
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials provided")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+type Principal struct {
+	Subject string
+	Roles   []Role
+}
+
+func (p *Principal) HasRole(role Role) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) CanSeePII() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+func (p *Principal) CanSeeMaskedPII() bool {
+	return p.HasRole(RoleAdmin) || p.HasRole(RoleSupport) || p.HasRole(RoleFinance)
+}
+
+func (p *Principal) CanSeePayment() bool {
+	return p.HasRole(RoleAdmin) || p.HasRole(RoleFinance)
+}
+
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type Authenticators []Authenticator
+
+func (a Authenticators) Authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range a {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrNoCredentials
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+func parseRoles(values []string) []Role {
+	roles := make([]Role, 0, len(values))
+	for _, v := range values {
+		switch Role(v) {
+		case RoleSupport, RoleFinance, RoleAdmin:
+			roles = append(roles, Role(v))
+		}
+	}
+	return roles
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+)
+
+func signHS256(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	unsigned := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	unsigned := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	secret := []byte("test-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	authenticator := NewJWTAuthenticator(secret, &rsaKey.PublicKey, "wbtech", "orders")
+
+	valid := map[string]any{
+		"sub":   "agent-1",
+		"iss":   "wbtech",
+		"aud":   []string{"orders"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"support", "unknown"},
+	}
+
+	principal, err := authenticator.Verify(signHS256(t, secret, valid))
+	if err != nil {
+		t.Fatalf("Expected HS256 token to verify, got %v", err)
+	}
+	if principal.Subject != "agent-1" || !principal.HasRole(RoleSupport) || len(principal.Roles) != 1 {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+
+	if _, err := authenticator.Verify(signRS256(t, rsaKey, valid)); err != nil {
+		t.Errorf("Expected RS256 token to verify, got %v", err)
+	}
+
+	if _, err := authenticator.Verify(signHS256(t, []byte("other"), valid)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected bad signature to be rejected, got %v", err)
+	}
+
+	expired := map[string]any{"sub": "agent-1", "iss": "wbtech", "aud": "orders", "exp": time.Now().Add(-time.Hour).Unix()}
+	if _, err := authenticator.Verify(signHS256(t, secret, expired)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+
+	wrongAudience := map[string]any{"sub": "agent-1", "iss": "wbtech", "aud": "billing", "exp": time.Now().Add(time.Hour).Unix()}
+	if _, err := authenticator.Verify(signHS256(t, secret, wrongAudience)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected wrong audience to be rejected, got %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/order/1", nil)
+	if _, err := authenticator.Authenticate(req); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected missing token to report no credentials, got %v", err)
+	}
+}
+
+func TestAuthenticators(t *testing.T) {
+	keys := NewAPIKeyAuthenticator([]APIKey{{Key: "finance-key", Subject: "reconciliation", Roles: []string{"finance"}}})
+	authenticator := Authenticators{keys, NewJWTAuthenticator([]byte("secret"), nil, "", "")}
+
+	req := httptest.NewRequest("GET", "/order/1", nil)
+	req.Header.Set("X-API-Key", "finance-key")
+	principal, err := authenticator.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Expected API key to authenticate, got %v", err)
+	}
+	if ViewFor(principal) != ViewFinance {
+		t.Errorf("Expected finance view, got %s", ViewFor(principal))
+	}
+
+	req.Header.Set("X-API-Key", "unknown")
+	if _, err := authenticator.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected unknown API key to be rejected, got %v", err)
+	}
+}
+
+func TestMaskDelivery(t *testing.T) {
+	masked := MaskDelivery(db.Delivery{
+		Name:    "Test Testov",
+		Phone:   "+9720000000",
+		Zip:     "2639809",
+		City:    "Kiryat Mozkin",
+		Address: "Ploshad Mira 15",
+		Email:   "test@gmail.com",
+	})
+
+	expected := db.Delivery{
+		Name:    "T*** T*****",
+		Phone:   "+******0000",
+		Zip:     "*******",
+		City:    "Kiryat Mozkin",
+		Address: "P****** M*** 1*",
+		Email:   "t***@gmail.com",
+	}
+	if masked != expected {
+		t.Errorf("Expected %+v, got %+v", expected, masked)
+	}
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const clockSkew = 30 * time.Second
+
+type JWTAuthenticator struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	issuer     string
+	audience   string
+	now        func() time.Time
+}
+
+func NewJWTAuthenticator(hmacSecret []byte, rsaKey *rsa.PublicKey, issuer, audience string) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		hmacSecret: hmacSecret,
+		rsaKey:     rsaKey,
+		issuer:     issuer,
+		audience:   audience,
+		now:        time.Now,
+	}
+}
+
+func LoadJWTAuthenticator(secretFile, publicKeyFile, issuer, audience string) (*JWTAuthenticator, error) {
+	var secret []byte
+	if secretFile != "" {
+		data, err := os.ReadFile(secretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = []byte(strings.TrimSpace(string(data)))
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("HS256 secret file %s is empty", secretFile)
+		}
+	}
+
+	var publicKey *rsa.PublicKey
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err = ParseRSAPublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing RS256 public key %s: %w", publicKeyFile, err)
+		}
+	}
+
+	return NewJWTAuthenticator(secret, publicKey, issuer, audience), nil
+}
+
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not RSA")
+		}
+		return rsaKey, nil
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		rsaKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("certificate key is not RSA")
+		}
+		return rsaKey, nil
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	return a.Verify(strings.TrimSpace(token))
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Roles     []string        `json:"roles"`
+	Role      string          `json:"role"`
+}
+
+func (a *JWTAuthenticator) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidCredentials)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidCredentials)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch header.Alg {
+	case "HS256":
+		if a.hmacSecret == nil {
+			return nil, fmt.Errorf("%w: HS256 not accepted", ErrInvalidCredentials)
+		}
+		mac := hmac.New(sha256.New, a.hmacSecret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidCredentials)
+		}
+	case "RS256":
+		if a.rsaKey == nil {
+			return nil, fmt.Errorf("%w: RS256 not accepted", ErrInvalidCredentials)
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(a.rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidCredentials)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidCredentials, header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims", ErrInvalidCredentials)
+	}
+
+	now := a.now()
+	if claims.ExpiresAt == nil || now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("%w: token not yet valid", ErrInvalidCredentials)
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+	if a.audience != "" && !audienceContains(claims.Audience, a.audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+
+	roles := claims.Roles
+	if claims.Role != "" {
+		roles = append(roles, claims.Role)
+	}
+	return &Principal{Subject: claims.Subject, Roles: parseRoles(roles)}, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func audienceContains(raw json.RawMessage, audience string) bool {
+	if len(raw) == 0 {
+		return false
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return false
+	}
+	for _, a := range many {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"strings"
+
+	"WBTechL0/internal/db"
+)
+
+type View string
+
+const (
+	ViewFull    View = "full"
+	ViewFinance View = "finance"
+	ViewSupport View = "support"
+	ViewNone    View = "none"
+)
+
+func ViewFor(p *Principal) View {
+	switch {
+	case p.CanSeePII() && p.CanSeePayment():
+		return ViewFull
+	case p.CanSeePayment():
+		return ViewFinance
+	case p.CanSeeMaskedPII():
+		return ViewSupport
+	}
+	return ViewNone
+}
+
+func Restrict(view View, delivery *db.Delivery, payment *db.Payment) (*db.Delivery, *db.Payment) {
+	switch view {
+	case ViewFull:
+		return delivery, payment
+	case ViewFinance, ViewSupport:
+		if delivery != nil {
+			masked := MaskDelivery(*delivery)
+			delivery = &masked
+		}
+		if view == ViewSupport {
+			payment = nil
+		}
+		return delivery, payment
+	}
+	return nil, nil
+}
+
+func MaskDelivery(d db.Delivery) db.Delivery {
+	d.Name = maskWords(d.Name)
+	d.Phone = maskKeepLast(d.Phone, 4)
+	d.Zip = maskKeepLast(d.Zip, 0)
+	d.Address = maskWords(d.Address)
+	d.Email = maskEmail(d.Email)
+	return d
+}
+
+func maskWords(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = string(r[0]) + strings.Repeat("*", len(r)-1)
+	}
+	return strings.Join(words, " ")
+}
+
+func maskKeepLast(s string, keep int) string {
+	r := []rune(s)
+	if len(r) <= keep {
+		return strings.Repeat("*", len(r))
+	}
+	masked := make([]rune, len(r))
+	for i := range r {
+		switch {
+		case i >= len(r)-keep:
+			masked[i] = r[i]
+		case i == 0 && r[i] == '+':
+			masked[i] = '+'
+		default:
+			masked[i] = '*'
+		}
+	}
+	return string(masked)
+}
+
+func maskEmail(s string) string {
+	local, domain, ok := strings.Cut(s, "@")
+	if !ok {
+		return maskKeepLast(s, 0)
+	}
+	return maskWords(local) + "@" + domain
+}
@@ -0,0 +1,18 @@
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+func Delay(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	ceiling := max
+	if attempt < 63 && base <= max>>attempt {
+		ceiling = base << attempt
+	}
+	half := ceiling / 2
+	return half + time.Duration(rand.Int63n(int64(ceiling-half)+1))
+}
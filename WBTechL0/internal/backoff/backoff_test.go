@@ -0,0 +1,24 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayBounds(t *testing.T) {
+	base, max := 10*time.Millisecond, time.Second
+	for attempt := 0; attempt < 70; attempt++ {
+		ceiling := max
+		if attempt < 30 && base<<attempt < max {
+			ceiling = base << attempt
+		}
+		d := Delay(attempt, base, max)
+		if d < ceiling/2 || d > ceiling {
+			t.Errorf("Attempt %d: delay %s outside [%s, %s]", attempt, d, ceiling/2, ceiling)
+		}
+	}
+
+	if d := Delay(3, 0, time.Second); d != 0 {
+		t.Errorf("Expected no delay without a base, got %s", d)
+	}
+}
@@ -2,15 +2,19 @@ package cache
 
 import (
 	"WBTechL0/internal/db"
+	"context"
 	"database/sql"
 	"github.com/patrickmn/go-cache"
 	"log"
+	"maps"
+	"sync"
 	"time"
 )
 
 type Cache struct {
 	cache *cache.Cache
 	db    *sql.DB
+	mu    sync.Mutex
 }
 
 func NewCache(db *sql.DB) *Cache {
@@ -21,7 +25,7 @@ func NewCache(db *sql.DB) *Cache {
 	}
 }
 
-func (c *Cache) GetOrder(orderID string) (*db.Order, error) {
+func (c *Cache) GetOrder(ctx context.Context, orderID string) (*db.Order, error) {
 	log.Println("Fetching order from cache or DB:", orderID)
 	if cachedOrder, found := c.cache.Get(orderID); found {
 		order := cachedOrder.(db.Order)
@@ -29,7 +33,7 @@ func (c *Cache) GetOrder(orderID string) (*db.Order, error) {
 		return &order, nil
 	}
 
-	order, err := c.fetchOrderFromDB(orderID)
+	order, err := c.fetchOrderFromDB(ctx, orderID)
 	if err != nil {
 		log.Println("Error fetching order from DB:", err)
 		return nil, err
@@ -40,7 +44,7 @@ func (c *Cache) GetOrder(orderID string) (*db.Order, error) {
 	return order, nil
 }
 
-func (c *Cache) GetDelivery(orderID string) (*db.Delivery, error) {
+func (c *Cache) GetDelivery(ctx context.Context, orderID string) (*db.Delivery, error) {
 	log.Println("Fetching delivery from cache or DB:", orderID)
 	if cachedDelivery, found := c.cache.Get(orderID + ":delivery"); found {
 		delivery := cachedDelivery.(db.Delivery)
@@ -48,7 +52,7 @@ func (c *Cache) GetDelivery(orderID string) (*db.Delivery, error) {
 		return &delivery, nil
 	}
 
-	delivery, err := c.fetchDeliveryFromDB(orderID)
+	delivery, err := c.fetchDeliveryFromDB(ctx, orderID)
 	if err != nil {
 		log.Println("Error fetching delivery from DB:", err)
 		return nil, err
@@ -59,7 +63,7 @@ func (c *Cache) GetDelivery(orderID string) (*db.Delivery, error) {
 	return delivery, nil
 }
 
-func (c *Cache) GetPayment(orderID string) (*db.Payment, error) {
+func (c *Cache) GetPayment(ctx context.Context, orderID string) (*db.Payment, error) {
 	log.Println("Fetching payment from cache or DB:", orderID)
 	if cachedPayment, found := c.cache.Get(orderID + ":payment"); found {
 		payment := cachedPayment.(db.Payment)
@@ -67,7 +71,7 @@ func (c *Cache) GetPayment(orderID string) (*db.Payment, error) {
 		return &payment, nil
 	}
 
-	payment, err := c.fetchPaymentFromDB(orderID)
+	payment, err := c.fetchPaymentFromDB(ctx, orderID)
 	if err != nil {
 		log.Println("Error fetching payment from DB:", err)
 		return nil, err
@@ -78,7 +82,7 @@ func (c *Cache) GetPayment(orderID string) (*db.Payment, error) {
 	return payment, nil
 }
 
-func (c *Cache) GetItems(orderID string) ([]db.Item, error) {
+func (c *Cache) GetItems(ctx context.Context, orderID string) ([]db.Item, error) {
 	log.Println("Fetching items from cache or DB:", orderID)
 	if cachedItems, found := c.cache.Get(orderID + ":items"); found {
 		items := cachedItems.([]db.Item)
@@ -86,7 +90,7 @@ func (c *Cache) GetItems(orderID string) ([]db.Item, error) {
 		return items, nil
 	}
 
-	items, err := c.fetchItemsFromDB(orderID)
+	items, err := c.fetchItemsFromDB(ctx, orderID)
 	if err != nil {
 		log.Println("Error fetching items from DB:", err)
 		return nil, err
@@ -97,15 +101,15 @@ func (c *Cache) GetItems(orderID string) ([]db.Item, error) {
 	return items, nil
 }
 
-func (c *Cache) fetchOrderFromDB(orderID string) (*db.Order, error) {
+func (c *Cache) fetchOrderFromDB(ctx context.Context, orderID string) (*db.Order, error) {
 	log.Println("Querying order from DB:", orderID)
-	var order db.Order
-	err := c.db.QueryRow(`
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	order, err := db.ScanOrder(c.db.QueryRowContext(ctx, `
         SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
         FROM orders
-        WHERE order_uid = $1`, orderID).Scan(
-		&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
-		&order.CustomerID, &order.DeliveryService, &order.ShardKey, &order.SMID, &order.DateCreated, &order.OOFShard)
+        WHERE order_uid = $1`, orderID))
 	if err != nil {
 		log.Println("Error querying order from DB:", err)
 		return nil, err
@@ -113,10 +117,13 @@ func (c *Cache) fetchOrderFromDB(orderID string) (*db.Order, error) {
 	return &order, nil
 }
 
-func (c *Cache) fetchDeliveryFromDB(orderID string) (*db.Delivery, error) {
+func (c *Cache) fetchDeliveryFromDB(ctx context.Context, orderID string) (*db.Delivery, error) {
 	log.Println("Querying delivery from DB:", orderID)
 	var delivery db.Delivery
-	err := c.db.QueryRow(`SELECT order_uid, name, phone, zip, city, address, region, email FROM delivery WHERE order_uid = $1`, orderID).Scan(
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	err := c.db.QueryRowContext(ctx, `SELECT order_uid, name, phone, zip, city, address, region, email FROM delivery WHERE order_uid = $1`, orderID).Scan(
 		&delivery.OrderUID, &delivery.Name, &delivery.Phone, &delivery.Zip, &delivery.City, &delivery.Address, &delivery.Region, &delivery.Email)
 	if err != nil {
 		log.Println("Error querying delivery from DB:", err)
@@ -126,10 +133,13 @@ func (c *Cache) fetchDeliveryFromDB(orderID string) (*db.Delivery, error) {
 	return &delivery, nil
 }
 
-func (c *Cache) fetchPaymentFromDB(orderID string) (*db.Payment, error) {
+func (c *Cache) fetchPaymentFromDB(ctx context.Context, orderID string) (*db.Payment, error) {
 	log.Println("Querying payment from DB:", orderID)
 	var payment db.Payment
-	err := c.db.QueryRow(`SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee, order_uid FROM payment WHERE order_uid = $1`, orderID).Scan(
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	err := c.db.QueryRowContext(ctx, `SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee, order_uid FROM payment WHERE order_uid = $1`, orderID).Scan(
 		&payment.Transaction, &payment.RequestID, &payment.Currency, &payment.Provider, &payment.Amount, &payment.PaymentDt, &payment.Bank, &payment.DeliveryCost, &payment.GoodsTotal, &payment.CustomFee, &payment.OrderUID)
 	if err != nil {
 		log.Println("Error querying payment from DB:", err)
@@ -138,9 +148,12 @@ func (c *Cache) fetchPaymentFromDB(orderID string) (*db.Payment, error) {
 	return &payment, nil
 }
 
-func (c *Cache) fetchItemsFromDB(orderID string) ([]db.Item, error) {
+func (c *Cache) fetchItemsFromDB(ctx context.Context, orderID string) ([]db.Item, error) {
 	log.Println("Querying items from DB:", orderID)
-	rows, err := c.db.Query(`SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid FROM items WHERE order_uid = $1`, orderID)
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid FROM items WHERE order_uid = $1`, orderID)
 	if err != nil {
 		log.Println("Error querying items from DB:", err)
 		return nil, err
@@ -163,9 +176,9 @@ func (c *Cache) fetchItemsFromDB(orderID string) ([]db.Item, error) {
 	return items, nil
 }
 
-func (c *Cache) LoadCacheFromDB() error {
+func (c *Cache) LoadCacheFromDB(ctx context.Context) error {
 	log.Println("Loading cache from DB")
-	rows, err := c.db.Query(`SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard FROM orders`)
+	rows, err := c.db.QueryContext(ctx, `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard FROM orders`)
 	if err != nil {
 		log.Println("Error querying orders for cache loading:", err)
 		return err
@@ -173,8 +186,7 @@ func (c *Cache) LoadCacheFromDB() error {
 	defer rows.Close()
 
 	for rows.Next() {
-		var order db.Order
-		err := rows.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.ShardKey, &order.SMID, &order.DateCreated, &order.OOFShard)
+		order, err := db.ScanOrder(rows)
 		if err != nil {
 			log.Println("Error scanning order row for cache:", err)
 			return err
@@ -184,3 +196,153 @@ func (c *Cache) LoadCacheFromDB() error {
 	}
 	return nil
 }
+
+func (c *Cache) GetResponse(orderID, variant string) ([]byte, bool) {
+	if cachedResponses, found := c.cache.Get(orderID + ":responses"); found {
+		body, ok := cachedResponses.(map[string][]byte)[variant]
+		return body, ok
+	}
+	return nil, false
+}
+
+func (c *Cache) SetResponse(orderID, variant string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	responses := make(map[string][]byte)
+	if cachedResponses, found := c.cache.Get(orderID + ":responses"); found {
+		responses = maps.Clone(cachedResponses.(map[string][]byte))
+	}
+	responses[variant] = body
+	c.cache.Set(orderID+":responses", responses, cache.DefaultExpiration)
+}
+
+func (c *Cache) Invalidate(orderID string) {
+	log.Println("Invalidating cached order:", orderID)
+	c.cache.Delete(orderID)
+	c.cache.Delete(orderID + ":delivery")
+	c.cache.Delete(orderID + ":payment")
+	c.cache.Delete(orderID + ":items")
+
+	c.mu.Lock()
+	c.cache.Delete(orderID + ":responses")
+	c.mu.Unlock()
+}
+
+type OrderAggregate struct {
+	Order    db.Order
+	Delivery *db.Delivery
+	Payment  *db.Payment
+	Items    []db.Item
+}
+
+func (c *Cache) GetOrdersBatch(ctx context.Context, orderIDs []string) ([]OrderAggregate, []string, error) {
+	log.Println("Fetching order batch from cache or DB:", len(orderIDs))
+
+	var unique []string
+	seen := make(map[string]struct{}, len(orderIDs))
+	for _, orderID := range orderIDs {
+		if _, ok := seen[orderID]; ok {
+			continue
+		}
+		seen[orderID] = struct{}{}
+		unique = append(unique, orderID)
+	}
+
+	orders := make(map[string]db.Order, len(unique))
+	deliveries := make(map[string]db.Delivery, len(unique))
+	payments := make(map[string]db.Payment, len(unique))
+	items := make(map[string][]db.Item, len(unique))
+	var missingOrders, missingDeliveries, missingPayments, missingItems []string
+
+	for _, orderID := range unique {
+		if cached, found := c.cache.Get(orderID); found {
+			orders[orderID] = cached.(db.Order)
+		} else {
+			missingOrders = append(missingOrders, orderID)
+		}
+		if cached, found := c.cache.Get(orderID + ":delivery"); found {
+			deliveries[orderID] = cached.(db.Delivery)
+		} else {
+			missingDeliveries = append(missingDeliveries, orderID)
+		}
+		if cached, found := c.cache.Get(orderID + ":payment"); found {
+			payments[orderID] = cached.(db.Payment)
+		} else {
+			missingPayments = append(missingPayments, orderID)
+		}
+		if cached, found := c.cache.Get(orderID + ":items"); found {
+			items[orderID] = cached.([]db.Item)
+		} else {
+			missingItems = append(missingItems, orderID)
+		}
+	}
+	log.Printf("Order batch cache misses: orders=%d delivery=%d payment=%d items=%d\n",
+		len(missingOrders), len(missingDeliveries), len(missingPayments), len(missingItems))
+
+	if len(missingOrders) > 0 {
+		fetched, err := db.GetOrdersByUIDs(ctx, c.db, missingOrders)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, order := range fetched {
+			orders[order.OrderUID] = order
+			c.cache.Set(order.OrderUID, order, cache.DefaultExpiration)
+		}
+	}
+	if len(missingDeliveries) > 0 {
+		fetched, err := db.GetDeliveriesByUIDs(ctx, c.db, missingDeliveries)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, delivery := range fetched {
+			deliveries[delivery.OrderUID] = delivery
+			c.cache.Set(delivery.OrderUID+":delivery", delivery, cache.DefaultExpiration)
+		}
+	}
+	if len(missingPayments) > 0 {
+		fetched, err := db.GetPaymentsByUIDs(ctx, c.db, missingPayments)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, payment := range fetched {
+			payments[payment.OrderUID] = payment
+			c.cache.Set(payment.OrderUID+":payment", payment, cache.DefaultExpiration)
+		}
+	}
+	if len(missingItems) > 0 {
+		fetched, err := db.GetItemsByUIDs(ctx, c.db, missingItems)
+		if err != nil {
+			return nil, nil, err
+		}
+		fetchedItems := make(map[string][]db.Item, len(missingItems))
+		for _, item := range fetched {
+			fetchedItems[item.OrderUID] = append(fetchedItems[item.OrderUID], item)
+		}
+		for _, orderID := range missingItems {
+			if _, ok := orders[orderID]; !ok {
+				continue
+			}
+			items[orderID] = fetchedItems[orderID]
+			c.cache.Set(orderID+":items", fetchedItems[orderID], cache.DefaultExpiration)
+		}
+	}
+
+	var found []OrderAggregate
+	var missing []string
+	for _, orderID := range unique {
+		order, ok := orders[orderID]
+		if !ok {
+			missing = append(missing, orderID)
+			continue
+		}
+		aggregate := OrderAggregate{Order: order, Items: items[orderID]}
+		if delivery, ok := deliveries[orderID]; ok {
+			aggregate.Delivery = &delivery
+		}
+		if payment, ok := payments[orderID]; ok {
+			aggregate.Payment = &payment
+		}
+		found = append(found, aggregate)
+	}
+	return found, missing, nil
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type OrderRecord struct {
+	Order    Order
+	Delivery Delivery
+	Payment  Payment
+	Items    []Item
+}
+
+var ErrDuplicateOrder = errors.New("order already exists")
+
+func filterExisting(ctx context.Context, tx *sql.Tx, records []OrderRecord) ([]OrderRecord, []int, []error, error) {
+	orderUIDs := make([]string, len(records))
+	for i, r := range records {
+		orderUIDs[i] = r.Order.OrderUID
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT order_uid FROM orders WHERE order_uid = ANY($1)`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+	seen := make(map[string]bool, len(records))
+	for rows.Next() {
+		var orderUID string
+		if err := rows.Scan(&orderUID); err != nil {
+			return nil, nil, nil, err
+		}
+		seen[orderUID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	errs := make([]error, len(records))
+	fresh := make([]OrderRecord, 0, len(records))
+	indexes := make([]int, 0, len(records))
+	for i, r := range records {
+		if seen[r.Order.OrderUID] {
+			errs[i] = fmt.Errorf("%w: %s", ErrDuplicateOrder, r.Order.OrderUID)
+			continue
+		}
+		seen[r.Order.OrderUID] = true
+		fresh = append(fresh, r)
+		indexes = append(indexes, i)
+	}
+	return fresh, indexes, errs, nil
+}
+
+func AddOrders(ctx context.Context, db *sql.DB, records []OrderRecord) ([]error, error) {
+	var errs []error
+	err := withRetry(ctx, "AddOrders", func() error {
+		var err error
+		errs, err = addOrders(ctx, db, records)
+		return err
+	})
+	return errs, err
+}
+
+func addOrders(ctx context.Context, db *sql.DB, records []OrderRecord) ([]error, error) {
+	ctx, cancel := WithWriteTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		`INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO payment (transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee, order_uid)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		`INSERT INTO items (chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+	}
+	prepared := make([]*sql.Stmt, len(statements))
+	for i, query := range statements {
+		prepared[i], err = tx.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer prepared[i].Close()
+	}
+	insertOrder, insertDelivery, insertPayment, insertItem := prepared[0], prepared[1], prepared[2], prepared[3]
+
+	fresh, indexes, errs, err := filterExisting(ctx, tx, records)
+	if err != nil {
+		return nil, err
+	}
+	for n, r := range fresh {
+		i := indexes[n]
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT order_record`); err != nil {
+			return nil, err
+		}
+
+		errs[i] = func() error {
+			o := r.Order
+			if _, err := insertOrder.ExecContext(ctx, o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID, o.DeliveryService, o.ShardKey, o.SMID, o.DateCreated, o.OOFShard); err != nil {
+				return fmt.Errorf("inserting order: %w", err)
+			}
+			d := r.Delivery
+			if _, err := insertDelivery.ExecContext(ctx, o.OrderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email); err != nil {
+				return fmt.Errorf("inserting delivery: %w", err)
+			}
+			p := r.Payment
+			if _, err := insertPayment.ExecContext(ctx, p.Transaction, p.RequestID, p.Currency, p.Provider, p.Amount, p.PaymentDt, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee, o.OrderUID); err != nil {
+				return fmt.Errorf("inserting payment: %w", err)
+			}
+			for _, item := range r.Items {
+				if _, err := insertItem.ExecContext(ctx, item.ChrtID, item.TrackNumber, item.Price, item.RID, item.Name, item.Sale, item.Size, item.TotalPrice, item.NMID, item.Brand, item.Status, o.OrderUID); err != nil {
+					return fmt.Errorf("inserting item %d: %w", item.ChrtID, err)
+				}
+			}
+			return nil
+		}()
+
+		if errs[i] != nil {
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT order_record`); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT order_record`); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := commit(tx); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+func CopyOrders(ctx context.Context, db *sql.DB, records []OrderRecord) ([]error, error) {
+	var errs []error
+	err := withRetry(ctx, "CopyOrders", func() error {
+		var err error
+		errs, err = copyOrders(ctx, db, records)
+		return err
+	})
+	return errs, err
+}
+
+func copyOrders(ctx context.Context, db *sql.DB, all []OrderRecord) ([]error, error) {
+	ctx, cancel := WithWriteTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	records, _, errs, err := filterExisting(ctx, tx, all)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return errs, nil
+	}
+
+	copyRows := func(table string, columns []string, rows func(stmt *sql.Stmt) error) error {
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+		if err != nil {
+			return err
+		}
+		if err := rows(stmt); err != nil {
+			stmt.Close()
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			stmt.Close()
+			return fmt.Errorf("copying into %s: %w", table, err)
+		}
+		return stmt.Close()
+	}
+
+	err = copyRows("orders", []string{"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard"}, func(stmt *sql.Stmt) error {
+		for _, r := range records {
+			o := r.Order
+			if _, err := stmt.ExecContext(ctx, o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID, o.DeliveryService, o.ShardKey, o.SMID, o.DateCreated, o.OOFShard); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = copyRows("delivery", []string{"order_uid", "name", "phone", "zip", "city", "address", "region", "email"}, func(stmt *sql.Stmt) error {
+		for _, r := range records {
+			d := r.Delivery
+			if _, err := stmt.ExecContext(ctx, r.Order.OrderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = copyRows("payment", []string{"transaction", "request_id", "currency", "provider", "amount", "payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee", "order_uid"}, func(stmt *sql.Stmt) error {
+		for _, r := range records {
+			p := r.Payment
+			if _, err := stmt.ExecContext(ctx, p.Transaction, p.RequestID, p.Currency, p.Provider, p.Amount, p.PaymentDt, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee, r.Order.OrderUID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = copyRows("items", []string{"chrt_id", "track_number", "price", "rid", "name", "sale", "size", "total_price", "nm_id", "brand", "status", "order_uid"}, func(stmt *sql.Stmt) error {
+		for _, r := range records {
+			for _, item := range r.Items {
+				if _, err := stmt.ExecContext(ctx, item.ChrtID, item.TrackNumber, item.Price, item.RID, item.Name, item.Sale, item.Size, item.TotalPrice, item.NMID, item.Brand, item.Status, r.Order.OrderUID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := commit(tx); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
@@ -1,11 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Order struct {
@@ -62,8 +64,17 @@ type Item struct {
 	Status      int    `json:"status"`
 }
 
-func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items []Item) error {
-	tx, err := db.Begin()
+func AddOrder(ctx context.Context, db *sql.DB, order Order, delivery Delivery, payment Payment, items []Item) error {
+	return withRetry(ctx, "AddOrder "+order.OrderUID, func() error {
+		return addOrder(ctx, db, order, delivery, payment, items)
+	})
+}
+
+func addOrder(ctx context.Context, db *sql.DB, order Order, delivery Delivery, payment Payment, items []Item) (err error) {
+	ctx, cancel := WithWriteTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Println("Error starting transaction:", err)
 		return err
@@ -76,31 +87,31 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 			log.Println("Error during transaction. Rolling back:", err)
 			tx.Rollback()
 		} else {
-			err = tx.Commit()
+			err = commit(tx)
 			if err != nil {
 				log.Println("Error committing transaction:", err)
 			} else {
 				log.Println("Transaction committed successfully")
 
 				var insertedOrder Order
-				err = db.QueryRow(`SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard 
+				selectErr := db.QueryRowContext(ctx, `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard 
                                    FROM orders WHERE order_uid = $1`, order.OrderUID).Scan(
 					&insertedOrder.OrderUID, &insertedOrder.TrackNumber, &insertedOrder.Entry, &insertedOrder.Locale,
 					&insertedOrder.InternalSignature, &insertedOrder.CustomerID, &insertedOrder.DeliveryService,
 					&insertedOrder.ShardKey, &insertedOrder.SMID, &insertedOrder.DateCreated, &insertedOrder.OOFShard)
-				if err != nil {
-					log.Println("Error querying inserted order:", err)
+				if selectErr != nil {
+					log.Println("Error querying inserted order:", selectErr)
 				} else {
 					log.Println("Inserted order:", insertedOrder)
 				}
 
 				var insertedDelivery Delivery
-				err = db.QueryRow(`SELECT order_uid, name, phone, zip, city, address, region, email 
+				selectErr = db.QueryRowContext(ctx, `SELECT order_uid, name, phone, zip, city, address, region, email 
                                    FROM delivery WHERE order_uid = $1`, order.OrderUID).Scan(
 					&insertedDelivery.OrderUID, &insertedDelivery.Name, &insertedDelivery.Phone, &insertedDelivery.Zip,
 					&insertedDelivery.City, &insertedDelivery.Address, &insertedDelivery.Region, &insertedDelivery.Email)
-				if err != nil {
-					log.Println("Error querying inserted delivery:", err)
+				if selectErr != nil {
+					log.Println("Error querying inserted delivery:", selectErr)
 				} else {
 					log.Println("Inserted delivery:", insertedDelivery)
 				}
@@ -108,14 +119,14 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 		}
 	}()
 
-	_, err = tx.Exec(`SET session_replication_role = 'replica'`)
+	_, err = tx.ExecContext(ctx, `SET session_replication_role = 'replica'`)
 	if err != nil {
 		log.Println("Error disabling foreign key checks:", err)
 		return err
 	}
 
 	log.Printf("Inserting into orders table: %+v\n", order)
-	result, err := tx.Exec(`
+	result, err := tx.ExecContext(ctx, `
         INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
 		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.ShardKey, order.SMID, order.DateCreated, order.OOFShard)
@@ -128,7 +139,7 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 	log.Println("Rows affected in orders table:", rowsAffected)
 	log.Printf("OrderUID for delivery before insert: %s\n", delivery.OrderUID)
 	log.Printf("Inserting into delivery table: %+v\n", delivery)
-	result, err = tx.Exec(`
+	result, err = tx.ExecContext(ctx, `
         INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
 		delivery.OrderUID, delivery.Name, delivery.Phone, delivery.Zip, delivery.City, delivery.Address, delivery.Region, delivery.Email)
@@ -141,7 +152,7 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 	log.Println("Rows affected in delivery table:", rowsAffected)
 
 	var insertedDelivery Delivery
-	err = tx.QueryRow(`SELECT order_uid, name, phone, zip, city, address, region, email 
+	err = tx.QueryRowContext(ctx, `SELECT order_uid, name, phone, zip, city, address, region, email 
                        FROM delivery WHERE order_uid = $1`, delivery.OrderUID).Scan(
 		&insertedDelivery.OrderUID, &insertedDelivery.Name, &insertedDelivery.Phone, &insertedDelivery.Zip,
 		&insertedDelivery.City, &insertedDelivery.Address, &insertedDelivery.Region, &insertedDelivery.Email)
@@ -152,7 +163,7 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 	}
 
 	log.Printf("Inserting into payment table: %+v\n", payment)
-	result, err = tx.Exec(`
+	result, err = tx.ExecContext(ctx, `
         INSERT INTO payment (transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee, order_uid)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
 		payment.Transaction, payment.RequestID, payment.Currency, payment.Provider, payment.Amount, payment.PaymentDt, payment.Bank, payment.DeliveryCost, payment.GoodsTotal, payment.CustomFee, order.OrderUID)
@@ -166,7 +177,7 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 
 	for _, item := range items {
 		log.Printf("Inserting into items table: %+v\n", item)
-		result, err = tx.Exec(`
+		result, err = tx.ExecContext(ctx, `
             INSERT INTO items (chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid)
             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
 			item.ChrtID, item.TrackNumber, item.Price, item.RID, item.Name, item.Sale, item.Size, item.TotalPrice, item.NMID, item.Brand, item.Status, order.OrderUID)
@@ -179,7 +190,7 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 		log.Println("Rows affected in items table:", rowsAffected)
 	}
 
-	_, err = tx.Exec(`SET session_replication_role = 'origin'`)
+	_, err = tx.ExecContext(ctx, `SET session_replication_role = 'origin'`)
 	if err != nil {
 		log.Println("Error enabling foreign key checks:", err)
 		return err
@@ -188,3 +199,31 @@ func AddOrder(db *sql.DB, order Order, delivery Delivery, payment Payment, items
 	log.Println("Transaction committed successfully")
 	return nil
 }
+
+func UpdateItemStatus(ctx context.Context, db *sql.DB, orderUID string, chrtID, status int) error {
+	return withRetry(ctx, "UpdateItemStatus "+orderUID, func() error {
+		ctx, cancel := WithWriteTimeout(ctx)
+		defer cancel()
+
+		result, err := db.ExecContext(ctx, `UPDATE items SET status = $3 WHERE order_uid = $1 AND chrt_id = $2`, orderUID, chrtID, status)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+func IsUniqueViolation(err error) bool {
+	if errors.Is(err, ErrDuplicateOrder) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
@@ -66,7 +67,7 @@ func TestAddOrder(t *testing.T) {
 		},
 	}
 
-	err = AddOrder(dbConn, order, delivery, payment, items)
+	err = AddOrder(context.Background(), dbConn, order, delivery, payment, items)
 	if err != nil {
 		t.Fatalf("Failed to add order: %v", err)
 	}
@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type OrderFilter struct {
+	CustomerID      string
+	DeliveryService string
+	CreatedFrom     time.Time
+	CreatedTo       time.Time
+	AfterUID        string
+	Limit           int
+}
+
+func (f OrderFilter) where() (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.Replace(condition, "?", "$"+strconv.Itoa(len(args)), 1))
+	}
+
+	if f.CustomerID != "" {
+		add("customer_id = ?", f.CustomerID)
+	}
+	if f.DeliveryService != "" {
+		add("delivery_service = ?", f.DeliveryService)
+	}
+	if !f.CreatedFrom.IsZero() {
+		add("date_created >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		add("date_created < ?", f.CreatedTo)
+	}
+	if f.AfterUID != "" {
+		add("order_uid > ?", f.AfterUID)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func ListOrders(ctx context.Context, db *sql.DB, filter OrderFilter) ([]Order, error) {
+	where, args := filter.where()
+	query := `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
+        FROM orders` + where + ` ORDER BY order_uid`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println("Error listing orders:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		order, err := ScanOrder(rows)
+		if err != nil {
+			log.Println("Error scanning listed order row:", err)
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating listed order rows:", err)
+		return nil, err
+	}
+	return orders, nil
+}
+
+func GetOrdersByUIDs(ctx context.Context, db *sql.DB, orderUIDs []string) ([]Order, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
+        FROM orders WHERE order_uid = ANY($1)`, pq.Array(orderUIDs))
+	if err != nil {
+		log.Println("Error querying orders by UIDs:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		order, err := ScanOrder(rows)
+		if err != nil {
+			log.Println("Error scanning order row:", err)
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func GetDeliveriesByUIDs(ctx context.Context, db *sql.DB, orderUIDs []string) ([]Delivery, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `SELECT order_uid, name, phone, zip, city, address, region, email FROM delivery WHERE order_uid = ANY($1)`, pq.Array(orderUIDs))
+	if err != nil {
+		log.Println("Error querying deliveries by UIDs:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var delivery Delivery
+		if err := rows.Scan(&delivery.OrderUID, &delivery.Name, &delivery.Phone, &delivery.Zip, &delivery.City, &delivery.Address, &delivery.Region, &delivery.Email); err != nil {
+			log.Println("Error scanning delivery row:", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func GetPaymentsByUIDs(ctx context.Context, db *sql.DB, orderUIDs []string) ([]Payment, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee, order_uid FROM payment WHERE order_uid = ANY($1)`, pq.Array(orderUIDs))
+	if err != nil {
+		log.Println("Error querying payments by UIDs:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []Payment
+	for rows.Next() {
+		var payment Payment
+		if err := rows.Scan(&payment.Transaction, &payment.RequestID, &payment.Currency, &payment.Provider, &payment.Amount, &payment.PaymentDt, &payment.Bank, &payment.DeliveryCost, &payment.GoodsTotal, &payment.CustomFee, &payment.OrderUID); err != nil {
+			log.Println("Error scanning payment row:", err)
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+func GetItemsByUIDs(ctx context.Context, db *sql.DB, orderUIDs []string) ([]Item, error) {
+	ctx, cancel := WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid FROM items WHERE order_uid = ANY($1) ORDER BY item_id`, pq.Array(orderUIDs))
+	if err != nil {
+		log.Println("Error querying items by UIDs:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.RID, &item.Name, &item.Sale, &item.Size, &item.TotalPrice, &item.NMID, &item.Brand, &item.Status, &item.OrderUID); err != nil {
+			log.Println("Error scanning item row:", err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func StreamOrders(ctx context.Context, db *sql.DB, filter OrderFilter, pageSize int, fn func(OrderRecord) error) error {
+	remaining := filter.Limit
+	for {
+		page := filter
+		page.Limit = pageSize
+		if remaining > 0 && remaining < pageSize {
+			page.Limit = remaining
+		}
+
+		orders, err := ListOrders(ctx, db, page)
+		if err != nil {
+			return err
+		}
+		if len(orders) == 0 {
+			return nil
+		}
+
+		records, err := loadRecords(ctx, db, orders)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+
+		if remaining > 0 {
+			remaining -= len(orders)
+			if remaining <= 0 {
+				return nil
+			}
+		}
+		if len(orders) < page.Limit {
+			return nil
+		}
+		filter.AfterUID = orders[len(orders)-1].OrderUID
+	}
+}
+
+func GetOrderRecordsByUIDs(ctx context.Context, db *sql.DB, orderUIDs []string) ([]OrderRecord, error) {
+	orders, err := GetOrdersByUIDs(ctx, db, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+	return loadRecords(ctx, db, orders)
+}
+
+func loadRecords(ctx context.Context, db *sql.DB, orders []Order) ([]OrderRecord, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	orderUIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderUIDs[i] = order.OrderUID
+	}
+	deliveries, err := GetDeliveriesByUIDs(ctx, db, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := GetPaymentsByUIDs(ctx, db, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+	items, err := GetItemsByUIDs(ctx, db, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]*OrderRecord, len(orders))
+	for _, order := range orders {
+		records[order.OrderUID] = &OrderRecord{Order: order}
+	}
+	for _, delivery := range deliveries {
+		records[delivery.OrderUID].Delivery = delivery
+	}
+	for _, payment := range payments {
+		records[payment.OrderUID].Payment = payment
+	}
+	for _, item := range items {
+		records[item.OrderUID].Items = append(records[item.OrderUID].Items, item)
+	}
+
+	result := make([]OrderRecord, len(orders))
+	for i, order := range orders {
+		result[i] = *records[order.OrderUID]
+	}
+	return result, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func ScanOrder(row rowScanner) (Order, error) {
+	var order Order
+	err := row.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+		&order.CustomerID, &order.DeliveryService, &order.ShardKey, &order.SMID, &order.DateCreated, &order.OOFShard)
+	order.DateCreated = order.DateCreated.UTC()
+	return order, err
+}
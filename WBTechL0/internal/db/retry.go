@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"WBTechL0/internal/backoff"
+	"github.com/lib/pq"
+)
+
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var retryPolicy = RetryPolicy{Attempts: 5, BaseDelay: 50 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+func SetRetryPolicy(p RetryPolicy) {
+	retryPolicy = p
+}
+
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func Open(ctx context.Context, url string, pool Pool, startupTimeout time.Duration) (*sql.DB, error) {
+	database, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+	database.SetMaxOpenConns(pool.MaxOpenConns)
+	database.SetMaxIdleConns(pool.MaxIdleConns)
+	database.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	database.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(ctx, startupTimeout)
+	defer cancel()
+	for attempt := 0; ; attempt++ {
+		err = database.PingContext(ctx)
+		if err == nil {
+			log.Println("Connected to database")
+			return database, nil
+		}
+		delay := backoff.Delay(attempt, 250*time.Millisecond, 5*time.Second)
+		log.Printf("Database is not ready (attempt %d), retrying in %s: %v\n", attempt+1, delay, err)
+		select {
+		case <-ctx.Done():
+			database.Close()
+			return nil, errors.Join(errors.New("database did not become ready in time"), err)
+		case <-time.After(delay):
+		}
+	}
+}
+
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "40001", pqErr.Code == "40P01":
+			return true
+		case strings.HasPrefix(string(pqErr.Code), "08"):
+			return true
+		case pqErr.Code == "57P01", pqErr.Code == "57P02", pqErr.Code == "57P03":
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "read" || opErr.Op == "write")
+}
+
+type commitError struct {
+	err error
+}
+
+func (e *commitError) Error() string {
+	return "commit: " + e.err.Error()
+}
+
+func (e *commitError) Unwrap() error {
+	return e.err
+}
+
+func commit(tx *sql.Tx) error {
+	err := tx.Commit()
+	var pqErr *pq.Error
+	if err == nil || errors.As(err, &pqErr) {
+		return err
+	}
+	return &commitError{err: err}
+}
+
+func withRetry(ctx context.Context, op string, fn func() error) error {
+	var err error
+	var commitErr *commitError
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || !IsTransient(err) || errors.As(err, &commitErr) || attempt+1 >= retryPolicy.Attempts {
+			return err
+		}
+		delay := backoff.Delay(attempt, retryPolicy.BaseDelay, retryPolicy.MaxDelay)
+		log.Printf("Transient error in %s (attempt %d), retrying in %s: %v\n", op, attempt+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
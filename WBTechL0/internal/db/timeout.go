@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type Timeouts struct {
+	Query time.Duration
+	Write time.Duration
+}
+
+var timeouts = Timeouts{Query: 3 * time.Second, Write: 10 * time.Second}
+
+func SetTimeouts(t Timeouts) {
+	timeouts = t
+}
+
+func WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, timeouts.Query)
+}
+
+func WithWriteTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, timeouts.Write)
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"WBTechL0/internal/db"
+)
+
+const subscriberBuffer = 64
+
+type OrderEvent struct {
+	ID       uint64
+	Time     time.Time
+	Order    db.Order
+	Delivery db.Delivery
+	Payment  db.Payment
+	Items    []db.Item
+}
+
+type Filter struct {
+	DeliveryService string
+	CustomerID      string
+}
+
+func (f Filter) Match(evt OrderEvent) bool {
+	if f.DeliveryService != "" && evt.Order.DeliveryService != f.DeliveryService {
+		return false
+	}
+	if f.CustomerID != "" && evt.Order.CustomerID != f.CustomerID {
+		return false
+	}
+	return true
+}
+
+type Publisher interface {
+	Publish(evt OrderEvent)
+}
+
+type PublisherFunc func(evt OrderEvent)
+
+func (f PublisherFunc) Publish(evt OrderEvent) {
+	f(evt)
+}
+
+type Subscription struct {
+	broker *Broker
+	filter Filter
+	events chan OrderEvent
+	once   sync.Once
+}
+
+func (s *Subscription) Events() <-chan OrderEvent {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+type Broker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []OrderEvent
+	historySize int
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Broker) Publish(evt OrderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	evt.ID = b.nextID
+	if evt.Time.IsZero() {
+		evt.Time = time.Now()
+	}
+
+	if b.historySize > 0 {
+		if len(b.history) >= b.historySize {
+			copy(b.history, b.history[1:])
+			b.history = b.history[:len(b.history)-1]
+		}
+		b.history = append(b.history, evt)
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Match(evt) {
+			continue
+		}
+		select {
+		case sub.events <- evt:
+		default:
+			log.Println("Dropping slow event subscriber, last delivered event:", evt.ID-1)
+			b.closeLocked(sub)
+		}
+	}
+}
+
+func (b *Broker) Subscribe(filter Filter, lastEventID uint64) (*Subscription, []OrderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{
+		broker: b,
+		filter: filter,
+		events: make(chan OrderEvent, subscriberBuffer),
+	}
+	b.subscribers[sub] = struct{}{}
+
+	var missed []OrderEvent
+	if lastEventID > 0 && lastEventID <= b.nextID {
+		for _, evt := range b.history {
+			if evt.ID > lastEventID && filter.Match(evt) {
+				missed = append(missed, evt)
+			}
+		}
+	}
+	return sub, missed
+}
+
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+func (b *Broker) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeLocked(sub)
+}
+
+func (b *Broker) closeLocked(sub *Subscription) {
+	sub.once.Do(func() {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	})
+}
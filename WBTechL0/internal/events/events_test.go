@@ -0,0 +1,59 @@
+package events
+
+import (
+	"testing"
+
+	"WBTechL0/internal/db"
+)
+
+func orderEvent(uid, service string) OrderEvent {
+	return OrderEvent{Order: db.Order{OrderUID: uid, DeliveryService: service, CustomerID: "test"}}
+}
+
+func TestBrokerFilterAndResume(t *testing.T) {
+	broker := NewBroker(2)
+
+	sub, missed := broker.Subscribe(Filter{DeliveryService: "meest"}, 0)
+	defer sub.Close()
+	if len(missed) != 0 {
+		t.Fatalf("Expected no replay for a new subscriber, got %d events", len(missed))
+	}
+
+	broker.Publish(orderEvent("a", "meest"))
+	broker.Publish(orderEvent("b", "dhl"))
+	broker.Publish(orderEvent("c", "meest"))
+
+	for _, expected := range []string{"a", "c"} {
+		evt := <-sub.Events()
+		if evt.Order.OrderUID != expected {
+			t.Errorf("Expected event for order %s, got %s", expected, evt.Order.OrderUID)
+		}
+	}
+
+	resumed, missed := broker.Subscribe(Filter{}, 1)
+	defer resumed.Close()
+	if len(missed) != 2 || missed[0].ID != 2 || missed[1].ID != 3 {
+		t.Errorf("Expected events 2 and 3 to be replayed, got %+v", missed)
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker(0)
+	sub, _ := broker.Subscribe(Filter{}, 0)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		broker.Publish(orderEvent("a", "meest"))
+	}
+
+	if broker.Subscribers() != 0 {
+		t.Fatalf("Expected slow subscriber to be removed, got %d subscribers", broker.Subscribers())
+	}
+	count := 0
+	for range sub.Events() {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("Expected %d buffered events before close, got %d", subscriberBuffer, count)
+	}
+	sub.Close()
+}
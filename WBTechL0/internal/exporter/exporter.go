@@ -0,0 +1,256 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"WBTechL0/internal/db"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+type Shape string
+
+const (
+	ShapeFlat   Shape = "flat"
+	ShapeNested Shape = "nested"
+)
+
+const parquetRowGroupSize = 8 << 20
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(value)) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON, "jsonl":
+		return FormatNDJSON, nil
+	case FormatParquet:
+		return FormatParquet, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q", value)
+}
+
+func ParseShape(value string) (Shape, error) {
+	switch Shape(strings.ToLower(value)) {
+	case ShapeFlat:
+		return ShapeFlat, nil
+	case ShapeNested:
+		return ShapeNested, nil
+	}
+	return "", fmt.Errorf("unsupported export shape %q", value)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/vnd.apache.parquet"
+}
+
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+type Writer interface {
+	Write(record db.OrderRecord) error
+	Flush() error
+	Close() error
+}
+
+func NewWriter(w io.Writer, format Format, shape Shape) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w), shape: shape}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w), shape: shape}, nil
+	case FormatParquet:
+		var schema any = new(flatRow)
+		if shape == ShapeNested {
+			schema = new(nestedRow)
+		}
+		pw, err := writer.NewParquetWriterFromWriter(w, schema, 1)
+		if err != nil {
+			return nil, err
+		}
+		pw.RowGroupSize = parquetRowGroupSize
+		pw.CompressionType = parquet.CompressionCodec_SNAPPY
+		return &parquetWriter{w: pw, shape: shape}, nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+func Export(w Writer, stream func(fn func(db.OrderRecord) error) error) (int, error) {
+	count := 0
+	err := stream(func(record db.OrderRecord) error {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
+
+type ndjsonWriter struct {
+	enc   *json.Encoder
+	shape Shape
+}
+
+func (w *ndjsonWriter) Write(record db.OrderRecord) error {
+	if w.shape == ShapeNested {
+		return w.enc.Encode(toNestedRow(record))
+	}
+	for _, row := range toFlatRows(record) {
+		if err := w.enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *ndjsonWriter) Flush() error { return nil }
+
+func (w *ndjsonWriter) Close() error { return nil }
+
+type csvWriter struct {
+	w           *csv.Writer
+	shape       Shape
+	wroteHeader bool
+}
+
+func (w *csvWriter) Write(record db.OrderRecord) error {
+	if w.shape == ShapeNested {
+		return w.writeRow(toNestedRow(record))
+	}
+	for _, row := range toFlatRows(record) {
+		if err := w.writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) writeRow(row any) error {
+	v := reflect.ValueOf(row)
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if err := w.w.Write(csvColumns(v.Type(), "")); err != nil {
+			return err
+		}
+	}
+	values, err := csvValues(v, nil)
+	if err != nil {
+		return err
+	}
+	return w.w.Write(values)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) Close() error {
+	if !w.wroteHeader {
+		var header []string
+		if w.shape == ShapeNested {
+			header = csvColumns(reflect.TypeOf(nestedRow{}), "")
+		} else {
+			header = csvColumns(reflect.TypeOf(flatRow{}), "")
+		}
+		if err := w.w.Write(header); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func csvColumns(t reflect.Type, prefix string) []string {
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Type.Kind() == reflect.Struct {
+			columns = append(columns, csvColumns(field.Type, prefix+name+"_")...)
+			continue
+		}
+		columns = append(columns, prefix+name)
+	}
+	return columns
+}
+
+func csvValues(v reflect.Value, values []string) ([]string, error) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			var err error
+			if values, err = csvValues(field, values); err != nil {
+				return nil, err
+			}
+			continue
+		case reflect.Slice:
+			data, err := json.Marshal(field.Interface())
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, string(data))
+			continue
+		case reflect.Pointer:
+			if field.IsNil() {
+				values = append(values, "")
+				continue
+			}
+			field = field.Elem()
+		}
+		switch field.Kind() {
+		case reflect.Int64:
+			values = append(values, strconv.FormatInt(field.Int(), 10))
+		default:
+			values = append(values, field.String())
+		}
+	}
+	return values, nil
+}
+
+type parquetWriter struct {
+	w     *writer.ParquetWriter
+	shape Shape
+}
+
+func (w *parquetWriter) Write(record db.OrderRecord) error {
+	if w.shape == ShapeNested {
+		return w.w.Write(toNestedRow(record))
+	}
+	for _, row := range toFlatRows(record) {
+		if err := w.w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *parquetWriter) Flush() error { return nil }
+
+func (w *parquetWriter) Close() error {
+	return w.w.WriteStop()
+}
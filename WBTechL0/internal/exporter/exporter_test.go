@@ -0,0 +1,159 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func testRecords() []db.OrderRecord {
+	created := time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)
+	return []db.OrderRecord{
+		{
+			Order:    db.Order{OrderUID: "a", TrackNumber: "WBILMTESTTRACK", DateCreated: created, SMID: 99},
+			Delivery: db.Delivery{Name: "Test Testov", City: "Kiryat Mozkin"},
+			Payment:  db.Payment{Transaction: "a", Currency: "USD", Amount: 1817},
+			Items: []db.Item{
+				{ChrtID: 9934930, Name: "Mascaras", Price: 453},
+				{ChrtID: 9934931, Name: "Lipstick", Price: 120},
+			},
+		},
+		{
+			Order:   db.Order{OrderUID: "b", DateCreated: created},
+			Payment: db.Payment{Transaction: "b", Currency: "RUB"},
+		},
+	}
+}
+
+func export(t *testing.T, format Format, shape Shape) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, shape)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	count, err := Export(w, func(fn func(db.OrderRecord) error) error {
+		for _, r := range testRecords() {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 exported orders, got %d", count)
+	}
+	return buf.Bytes()
+}
+
+func TestExportCSV(t *testing.T) {
+	flat, err := csv.NewReader(bytes.NewReader(export(t, FormatCSV, ShapeFlat))).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(flat) != 4 {
+		t.Fatalf("Expected header and 3 rows, got %d", len(flat))
+	}
+	header := strings.Join(flat[0], ",")
+	if !strings.HasPrefix(header, "order_uid,track_number,") || !strings.Contains(header, ",delivery_city,") || !strings.HasSuffix(header, ",item_status") {
+		t.Errorf("Unexpected flat header: %s", header)
+	}
+	if flat[1][0] != "a" || flat[2][0] != "a" || flat[3][0] != "b" || flat[3][len(flat[3])-1] != "" {
+		t.Errorf("Unexpected flat rows: %v", flat[1:])
+	}
+
+	nested, err := csv.NewReader(bytes.NewReader(export(t, FormatCSV, ShapeNested))).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(nested) != 3 || nested[0][len(nested[0])-1] != "items" {
+		t.Fatalf("Expected header and 2 nested rows, got %v", nested)
+	}
+	var items []itemRow
+	if err := json.Unmarshal([]byte(nested[1][len(nested[1])-1]), &items); err != nil || len(items) != 2 {
+		t.Errorf("Expected items column to hold 2 items as JSON, got %q", nested[1][len(nested[1])-1])
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(export(t, FormatNDJSON, ShapeNested))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 nested lines, got %d", len(lines))
+	}
+	var row nestedRow
+	if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatalf("Failed to decode nested line: %v", err)
+	}
+	if row.OrderUID != "a" || row.DateCreated != "2021-11-26T06:22:19Z" || row.Delivery.City != "Kiryat Mozkin" || len(row.Items) != 2 {
+		t.Errorf("Unexpected nested row: %+v", row)
+	}
+
+	flatLines := strings.Split(strings.TrimSpace(string(export(t, FormatNDJSON, ShapeFlat))), "\n")
+	if len(flatLines) != 3 || !strings.Contains(flatLines[1], `"item_name":"Lipstick"`) || !strings.Contains(flatLines[2], `"item_name":null`) {
+		t.Errorf("Unexpected flat lines: %v", flatLines)
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	for _, tc := range []struct {
+		shape Shape
+		rows  int64
+	}{
+		{ShapeFlat, 3},
+		{ShapeNested, 2},
+	} {
+		data := export(t, FormatParquet, tc.shape)
+		if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+			t.Fatalf("%s: output is not a parquet file", tc.shape)
+		}
+
+		file, err := buffer.NewBufferFile(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema any = new(flatRow)
+		if tc.shape == ShapeNested {
+			schema = new(nestedRow)
+		}
+		pr, err := reader.NewParquetReader(file, schema, 1)
+		if err != nil {
+			t.Fatalf("%s: failed to open parquet file: %v", tc.shape, err)
+		}
+		if pr.GetNumRows() != tc.rows {
+			t.Errorf("%s: expected %d rows, got %d", tc.shape, tc.rows, pr.GetNumRows())
+		}
+
+		if tc.shape == ShapeNested {
+			rows := make([]nestedRow, tc.rows)
+			if err := pr.Read(&rows); err != nil {
+				t.Fatalf("Failed to read nested rows: %v", err)
+			}
+			if rows[0].OrderUID != "a" || len(rows[0].Items) != 2 || rows[0].Items[1].Name != "Lipstick" || rows[1].Payment.Currency != "RUB" {
+				t.Errorf("Unexpected nested parquet rows: %+v", rows)
+			}
+		}
+		pr.ReadStop()
+	}
+}
+
+func TestParseFormatAndShape(t *testing.T) {
+	if f, err := ParseFormat("JSONL"); err != nil || f != FormatNDJSON {
+		t.Errorf("Expected jsonl to map to ndjson, got %q %v", f, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("Expected unsupported format to fail")
+	}
+	if _, err := ParseShape("tree"); err == nil {
+		t.Error("Expected unsupported shape to fail")
+	}
+}
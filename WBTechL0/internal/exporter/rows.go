@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"time"
+
+	"WBTechL0/internal/db"
+)
+
+type deliveryRow struct {
+	Name    string `json:"name" parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Phone   string `json:"phone" parquet:"name=phone, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Zip     string `json:"zip" parquet:"name=zip, type=BYTE_ARRAY, convertedtype=UTF8"`
+	City    string `json:"city" parquet:"name=city, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Address string `json:"address" parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Region  string `json:"region" parquet:"name=region, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Email   string `json:"email" parquet:"name=email, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+type paymentRow struct {
+	Transaction  string `json:"transaction" parquet:"name=transaction, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RequestID    string `json:"request_id" parquet:"name=request_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Currency     string `json:"currency" parquet:"name=currency, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Provider     string `json:"provider" parquet:"name=provider, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount       int64  `json:"amount" parquet:"name=amount, type=INT64"`
+	PaymentDt    int64  `json:"payment_dt" parquet:"name=payment_dt, type=INT64"`
+	Bank         string `json:"bank" parquet:"name=bank, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryCost int64  `json:"delivery_cost" parquet:"name=delivery_cost, type=INT64"`
+	GoodsTotal   int64  `json:"goods_total" parquet:"name=goods_total, type=INT64"`
+	CustomFee    int64  `json:"custom_fee" parquet:"name=custom_fee, type=INT64"`
+}
+
+type itemRow struct {
+	ChrtID      int64  `json:"chrt_id" parquet:"name=chrt_id, type=INT64"`
+	TrackNumber string `json:"track_number" parquet:"name=track_number, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Price       int64  `json:"price" parquet:"name=price, type=INT64"`
+	RID         string `json:"rid" parquet:"name=rid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Name        string `json:"name" parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Sale        int64  `json:"sale" parquet:"name=sale, type=INT64"`
+	Size        string `json:"size" parquet:"name=size, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TotalPrice  int64  `json:"total_price" parquet:"name=total_price, type=INT64"`
+	NMID        int64  `json:"nm_id" parquet:"name=nm_id, type=INT64"`
+	Brand       string `json:"brand" parquet:"name=brand, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Status      int64  `json:"status" parquet:"name=status, type=INT64"`
+}
+
+type nestedRow struct {
+	OrderUID          string      `json:"order_uid" parquet:"name=order_uid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TrackNumber       string      `json:"track_number" parquet:"name=track_number, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Entry             string      `json:"entry" parquet:"name=entry, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Locale            string      `json:"locale" parquet:"name=locale, type=BYTE_ARRAY, convertedtype=UTF8"`
+	InternalSignature string      `json:"internal_signature" parquet:"name=internal_signature, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CustomerID        string      `json:"customer_id" parquet:"name=customer_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryService   string      `json:"delivery_service" parquet:"name=delivery_service, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ShardKey          string      `json:"shardkey" parquet:"name=shardkey, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SMID              int64       `json:"sm_id" parquet:"name=sm_id, type=INT64"`
+	DateCreated       string      `json:"date_created" parquet:"name=date_created, type=BYTE_ARRAY, convertedtype=UTF8"`
+	OOFShard          string      `json:"oof_shard" parquet:"name=oof_shard, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Delivery          deliveryRow `json:"delivery" parquet:"name=delivery"`
+	Payment           paymentRow  `json:"payment" parquet:"name=payment"`
+	Items             []itemRow   `json:"items" parquet:"name=items, type=LIST"`
+}
+
+type flatRow struct {
+	OrderUID          string `json:"order_uid" parquet:"name=order_uid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TrackNumber       string `json:"track_number" parquet:"name=track_number, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Entry             string `json:"entry" parquet:"name=entry, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Locale            string `json:"locale" parquet:"name=locale, type=BYTE_ARRAY, convertedtype=UTF8"`
+	InternalSignature string `json:"internal_signature" parquet:"name=internal_signature, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CustomerID        string `json:"customer_id" parquet:"name=customer_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryService   string `json:"delivery_service" parquet:"name=delivery_service, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ShardKey          string `json:"shardkey" parquet:"name=shardkey, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SMID              int64  `json:"sm_id" parquet:"name=sm_id, type=INT64"`
+	DateCreated       string `json:"date_created" parquet:"name=date_created, type=BYTE_ARRAY, convertedtype=UTF8"`
+	OOFShard          string `json:"oof_shard" parquet:"name=oof_shard, type=BYTE_ARRAY, convertedtype=UTF8"`
+
+	DeliveryName    string `json:"delivery_name" parquet:"name=delivery_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryPhone   string `json:"delivery_phone" parquet:"name=delivery_phone, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryZip     string `json:"delivery_zip" parquet:"name=delivery_zip, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryCity    string `json:"delivery_city" parquet:"name=delivery_city, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryAddress string `json:"delivery_address" parquet:"name=delivery_address, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryRegion  string `json:"delivery_region" parquet:"name=delivery_region, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryEmail   string `json:"delivery_email" parquet:"name=delivery_email, type=BYTE_ARRAY, convertedtype=UTF8"`
+
+	PaymentTransaction  string `json:"payment_transaction" parquet:"name=payment_transaction, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentRequestID    string `json:"payment_request_id" parquet:"name=payment_request_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentCurrency     string `json:"payment_currency" parquet:"name=payment_currency, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentProvider     string `json:"payment_provider" parquet:"name=payment_provider, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentAmount       int64  `json:"payment_amount" parquet:"name=payment_amount, type=INT64"`
+	PaymentDt           int64  `json:"payment_dt" parquet:"name=payment_dt, type=INT64"`
+	PaymentBank         string `json:"payment_bank" parquet:"name=payment_bank, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentDeliveryCost int64  `json:"payment_delivery_cost" parquet:"name=payment_delivery_cost, type=INT64"`
+	PaymentGoodsTotal   int64  `json:"payment_goods_total" parquet:"name=payment_goods_total, type=INT64"`
+	PaymentCustomFee    int64  `json:"payment_custom_fee" parquet:"name=payment_custom_fee, type=INT64"`
+
+	ItemChrtID      *int64  `json:"item_chrt_id" parquet:"name=item_chrt_id, type=INT64, repetitiontype=OPTIONAL"`
+	ItemTrackNumber *string `json:"item_track_number" parquet:"name=item_track_number, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemPrice       *int64  `json:"item_price" parquet:"name=item_price, type=INT64, repetitiontype=OPTIONAL"`
+	ItemRID         *string `json:"item_rid" parquet:"name=item_rid, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemName        *string `json:"item_name" parquet:"name=item_name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemSale        *int64  `json:"item_sale" parquet:"name=item_sale, type=INT64, repetitiontype=OPTIONAL"`
+	ItemSize        *string `json:"item_size" parquet:"name=item_size, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemTotalPrice  *int64  `json:"item_total_price" parquet:"name=item_total_price, type=INT64, repetitiontype=OPTIONAL"`
+	ItemNMID        *int64  `json:"item_nm_id" parquet:"name=item_nm_id, type=INT64, repetitiontype=OPTIONAL"`
+	ItemBrand       *string `json:"item_brand" parquet:"name=item_brand, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemStatus      *int64  `json:"item_status" parquet:"name=item_status, type=INT64, repetitiontype=OPTIONAL"`
+}
+
+func toItemRow(item db.Item) itemRow {
+	return itemRow{
+		ChrtID:      int64(item.ChrtID),
+		TrackNumber: item.TrackNumber,
+		Price:       int64(item.Price),
+		RID:         item.RID,
+		Name:        item.Name,
+		Sale:        int64(item.Sale),
+		Size:        item.Size,
+		TotalPrice:  int64(item.TotalPrice),
+		NMID:        int64(item.NMID),
+		Brand:       item.Brand,
+		Status:      int64(item.Status),
+	}
+}
+
+func toNestedRow(r db.OrderRecord) nestedRow {
+	o, d, p := r.Order, r.Delivery, r.Payment
+	row := nestedRow{
+		OrderUID:          o.OrderUID,
+		TrackNumber:       o.TrackNumber,
+		Entry:             o.Entry,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerID:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		ShardKey:          o.ShardKey,
+		SMID:              int64(o.SMID),
+		DateCreated:       o.DateCreated.Format(time.RFC3339),
+		OOFShard:          o.OOFShard,
+		Delivery: deliveryRow{
+			Name:    d.Name,
+			Phone:   d.Phone,
+			Zip:     d.Zip,
+			City:    d.City,
+			Address: d.Address,
+			Region:  d.Region,
+			Email:   d.Email,
+		},
+		Payment: paymentRow{
+			Transaction:  p.Transaction,
+			RequestID:    p.RequestID,
+			Currency:     p.Currency,
+			Provider:     p.Provider,
+			Amount:       int64(p.Amount),
+			PaymentDt:    int64(p.PaymentDt),
+			Bank:         p.Bank,
+			DeliveryCost: int64(p.DeliveryCost),
+			GoodsTotal:   int64(p.GoodsTotal),
+			CustomFee:    int64(p.CustomFee),
+		},
+		Items: make([]itemRow, 0, len(r.Items)),
+	}
+	for _, item := range r.Items {
+		row.Items = append(row.Items, toItemRow(item))
+	}
+	return row
+}
+
+func toFlatRows(r db.OrderRecord) []flatRow {
+	n := toNestedRow(r)
+	base := flatRow{
+		OrderUID:            n.OrderUID,
+		TrackNumber:         n.TrackNumber,
+		Entry:               n.Entry,
+		Locale:              n.Locale,
+		InternalSignature:   n.InternalSignature,
+		CustomerID:          n.CustomerID,
+		DeliveryService:     n.DeliveryService,
+		ShardKey:            n.ShardKey,
+		SMID:                n.SMID,
+		DateCreated:         n.DateCreated,
+		OOFShard:            n.OOFShard,
+		DeliveryName:        n.Delivery.Name,
+		DeliveryPhone:       n.Delivery.Phone,
+		DeliveryZip:         n.Delivery.Zip,
+		DeliveryCity:        n.Delivery.City,
+		DeliveryAddress:     n.Delivery.Address,
+		DeliveryRegion:      n.Delivery.Region,
+		DeliveryEmail:       n.Delivery.Email,
+		PaymentTransaction:  n.Payment.Transaction,
+		PaymentRequestID:    n.Payment.RequestID,
+		PaymentCurrency:     n.Payment.Currency,
+		PaymentProvider:     n.Payment.Provider,
+		PaymentAmount:       n.Payment.Amount,
+		PaymentDt:           n.Payment.PaymentDt,
+		PaymentBank:         n.Payment.Bank,
+		PaymentDeliveryCost: n.Payment.DeliveryCost,
+		PaymentGoodsTotal:   n.Payment.GoodsTotal,
+		PaymentCustomFee:    n.Payment.CustomFee,
+	}
+	if len(n.Items) == 0 {
+		return []flatRow{base}
+	}
+
+	rows := make([]flatRow, 0, len(n.Items))
+	for i := range n.Items {
+		item := &n.Items[i]
+		row := base
+		row.ItemChrtID = &item.ChrtID
+		row.ItemTrackNumber = &item.TrackNumber
+		row.ItemPrice = &item.Price
+		row.ItemRID = &item.RID
+		row.ItemName = &item.Name
+		row.ItemSale = &item.Sale
+		row.ItemSize = &item.Size
+		row.ItemTotalPrice = &item.TotalPrice
+		row.ItemNMID = &item.NMID
+		row.ItemBrand = &item.Brand
+		row.ItemStatus = &item.Status
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,206 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"WBTechL0/internal/money"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrNoRate = errors.New("no exchange rate")
+
+type Rate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Value *big.Rat
+}
+
+type Conversion struct {
+	Money    money.Money
+	Rate     *big.Rat
+	RateDate time.Time
+}
+
+type pair struct {
+	base, quote string
+}
+
+type datedRate struct {
+	date  time.Time
+	value *big.Rat
+}
+
+type Table struct {
+	rates      map[pair][]datedRate
+	currencies []string
+}
+
+func NewTable(rates []Rate) (*Table, error) {
+	t := &Table{rates: make(map[pair][]datedRate)}
+	seen := make(map[string]bool)
+	for i, r := range rates {
+		base, err := money.LookupCurrency(r.Base)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i+1, err)
+		}
+		quote, err := money.LookupCurrency(r.Quote)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i+1, err)
+		}
+		if base.Code == quote.Code {
+			return nil, fmt.Errorf("rate %d: base and quote are both %s", i+1, base.Code)
+		}
+		if r.Value == nil || r.Value.Sign() <= 0 {
+			return nil, fmt.Errorf("rate %d: rate must be positive", i+1)
+		}
+
+		p := pair{base.Code, quote.Code}
+		t.rates[p] = append(t.rates[p], datedRate{date: day(r.Date), value: r.Value})
+		for _, code := range []string{base.Code, quote.Code} {
+			if !seen[code] {
+				seen[code] = true
+				t.currencies = append(t.currencies, code)
+			}
+		}
+	}
+
+	for p, dated := range t.rates {
+		sort.SliceStable(dated, func(i, j int) bool { return dated[i].date.Before(dated[j].date) })
+		deduped := dated[:0]
+		for _, d := range dated {
+			if n := len(deduped); n > 0 && deduped[n-1].date.Equal(d.date) {
+				deduped[n-1] = d
+				continue
+			}
+			deduped = append(deduped, d)
+		}
+		t.rates[p] = deduped
+	}
+	sort.Strings(t.currencies)
+	return t, nil
+}
+
+func (t *Table) Len() int {
+	n := 0
+	for _, dated := range t.rates {
+		n += len(dated)
+	}
+	return n
+}
+
+func (t *Table) Lookup(from, to string, at time.Time) (*big.Rat, time.Time, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	at = day(at)
+	if from == to {
+		return big.NewRat(1, 1), at, nil
+	}
+	if rate, date, ok := t.lookupPair(from, to, at); ok {
+		return rate, date, nil
+	}
+
+	var best *big.Rat
+	var bestDate time.Time
+	for _, pivot := range t.currencies {
+		if pivot == from || pivot == to {
+			continue
+		}
+		first, firstDate, ok := t.lookupPair(from, pivot, at)
+		if !ok {
+			continue
+		}
+		second, secondDate, ok := t.lookupPair(pivot, to, at)
+		if !ok {
+			continue
+		}
+		date := firstDate
+		if secondDate.Before(date) {
+			date = secondDate
+		}
+		if best == nil || date.After(bestDate) {
+			best, bestDate = new(big.Rat).Mul(first, second), date
+		}
+	}
+	if best == nil {
+		return nil, time.Time{}, fmt.Errorf("%w from %s to %s on %s", ErrNoRate, from, to, at.Format(dateLayout))
+	}
+	return best, bestDate, nil
+}
+
+func (t *Table) lookupPair(from, to string, at time.Time) (*big.Rat, time.Time, bool) {
+	direct, directDate, directOK := latest(t.rates[pair{from, to}], at)
+	inverse, inverseDate, inverseOK := latest(t.rates[pair{to, from}], at)
+	switch {
+	case directOK && (!inverseOK || !inverseDate.After(directDate)):
+		return direct, directDate, true
+	case inverseOK:
+		return new(big.Rat).Inv(inverse), inverseDate, true
+	}
+	return nil, time.Time{}, false
+}
+
+func latest(dated []datedRate, at time.Time) (*big.Rat, time.Time, bool) {
+	i := sort.Search(len(dated), func(i int) bool { return dated[i].date.After(at) })
+	if i == 0 {
+		return nil, time.Time{}, false
+	}
+	return dated[i-1].value, dated[i-1].date, true
+}
+
+func (t *Table) Convert(m money.Money, to money.Currency, at time.Time) (Conversion, error) {
+	rate, date, err := t.Lookup(m.Currency().Code, to.Code, at)
+	if err != nil {
+		return Conversion{}, err
+	}
+
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount()), rate)
+	if shift := to.Exponent - m.Currency().Exponent; shift != 0 {
+		scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+		if shift > 0 {
+			value.Mul(value, scale)
+		} else {
+			value.Quo(value, scale)
+		}
+	}
+
+	amount := round(value)
+	if !amount.IsInt64() {
+		return Conversion{}, money.ErrOverflow
+	}
+	return Conversion{Money: money.New(amount.Int64(), to), Rate: rate, RateDate: date}, nil
+}
+
+func round(r *big.Rat) *big.Int {
+	num, denom := new(big.Int).Abs(r.Num()), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, denom, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(denom) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
+
+func FormatRate(rate *big.Rat) string {
+	s := strings.TrimRight(rate.FloatString(10), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,204 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/money"
+)
+
+const ratesCSV = `# daily reference rates
+date,base,quote,rate
+2021-11-01,USD,RUB,71.25
+2021-11-26,USD,RUB,75
+2021-11-26,EUR,USD,1.1321
+2021-11-26,USD,JPY,113.5
+2021-11-26,USD,KWD,0.3025
+`
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func loadTable(t *testing.T) *Table {
+	t.Helper()
+	rates, err := ReadCSV(strings.NewReader(ratesCSV))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	table, err := NewTable(rates)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return table
+}
+
+func TestLookup(t *testing.T) {
+	table := loadTable(t)
+	cases := []struct {
+		from, to string
+		at       time.Time
+		rate     string
+		date     time.Time
+	}{
+		{"USD", "RUB", date(2021, 11, 1), "71.25", date(2021, 11, 1)},
+		{"USD", "RUB", time.Date(2021, 11, 25, 23, 59, 0, 0, time.UTC), "71.25", date(2021, 11, 1)},
+		{"USD", "RUB", date(2022, 1, 1), "75", date(2021, 11, 26)},
+		{"RUB", "USD", date(2021, 11, 26), "0.0133333333", date(2021, 11, 26)},
+		{"EUR", "RUB", date(2021, 11, 26), "84.9075", date(2021, 11, 26)},
+		{"usd", "usd", date(2000, 1, 1), "1", date(2000, 1, 1)},
+	}
+	for _, tc := range cases {
+		rate, d, err := table.Lookup(tc.from, tc.to, tc.at)
+		if err != nil {
+			t.Errorf("%s/%s at %s: expected no error, got %v", tc.from, tc.to, tc.at, err)
+			continue
+		}
+		if FormatRate(rate) != tc.rate || !d.Equal(tc.date) {
+			t.Errorf("%s/%s at %s: expected %s on %s, got %s on %s", tc.from, tc.to, tc.at, tc.rate, tc.date, FormatRate(rate), d)
+		}
+	}
+
+	if _, _, err := table.Lookup("USD", "RUB", date(2021, 10, 31)); !errors.Is(err, ErrNoRate) {
+		t.Errorf("Expected %v before the first rate, got %v", ErrNoRate, err)
+	}
+	if _, _, err := table.Lookup("USD", "GBP", date(2021, 11, 26)); !errors.Is(err, ErrNoRate) {
+		t.Errorf("Expected %v for an unknown pair, got %v", ErrNoRate, err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	table := loadTable(t)
+	cases := []struct {
+		amount   int64
+		from, to string
+		expected int64
+	}{
+		{1817, "USD", "RUB", 136275},
+		{1817, "USD", "JPY", 2062},
+		{1817, "USD", "KWD", 5496},
+		{1, "RUB", "USD", 0},
+		{-1817, "USD", "RUB", -136275},
+		{100, "EUR", "USD", 113},
+	}
+	for _, tc := range cases {
+		m, err := money.Parse(tc.amount, tc.from)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		to, _ := money.LookupCurrency(tc.to)
+		conversion, err := table.Convert(m, to, date(2021, 11, 26))
+		if err != nil {
+			t.Errorf("%d %s to %s: expected no error, got %v", tc.amount, tc.from, tc.to, err)
+			continue
+		}
+		if conversion.Money.Amount() != tc.expected || conversion.Money.Currency().Code != tc.to {
+			t.Errorf("%d %s to %s: expected %d, got %v", tc.amount, tc.from, tc.to, tc.expected, conversion.Money)
+		}
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	rates, err := ReadJSON(strings.NewReader(`[{"date":"2021-11-26","base":"USD","quote":"RUB","rate":75.5},{"date":"2021-11-27","base":"USD","quote":"RUB","rate":"76"}]`))
+	if err != nil || len(rates) != 2 || rates[0].Value.FloatString(1) != "75.5" {
+		t.Fatalf("Unexpected rates %v (%v)", rates, err)
+	}
+
+	for _, data := range []string{
+		`[{"date":"26.11.2021","base":"USD","quote":"RUB","rate":75}]`,
+		`[{"date":"2021-11-26","base":"USD","quote":"RUB","rate":"abc"}]`,
+		`[{"date":"2021-11-26","base":"USD","quote":"RUB","rate":75,"source":"cbr"}]`,
+	} {
+		if _, err := ReadJSON(strings.NewReader(data)); err == nil {
+			t.Errorf("Expected %s to fail", data)
+		}
+	}
+}
+
+func TestNewTableValidates(t *testing.T) {
+	for _, data := range []string{
+		"date,base,quote,rate\n2021-11-26,USD,XYZ,75\n",
+		"date,base,quote,rate\n2021-11-26,USD,USD,1\n",
+		"date,base,quote,rate\n2021-11-26,USD,RUB,0\n",
+		"date,base,quote,rate\n2021-11-26,USD,RUB,-75\n",
+	} {
+		rates, err := ReadCSV(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("Expected no error reading %q, got %v", data, err)
+		}
+		if _, err := NewTable(rates); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+
+	if _, err := ReadCSV(strings.NewReader("date,base,rate\n2021-11-26,USD,75\n")); err == nil {
+		t.Error("Expected a missing quote column to be rejected")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rates.csv")
+	if err := os.WriteFile(path, []byte(ratesCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	table, err := LoadFile(path)
+	if err != nil || table.Len() != 5 {
+		t.Fatalf("Expected 5 rates, got %v (%v)", table, err)
+	}
+
+	xmlPath := filepath.Join(dir, "rates.xml")
+	if err := os.WriteFile(xmlPath, []byte(ratesCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(xmlPath); err == nil {
+		t.Error("Expected an unsupported extension to be rejected")
+	}
+}
+
+func TestRatesReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	if err := os.WriteFile(path, []byte("date,base,quote,rate\n2021-11-01,USD,RUB,71.25\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	load := func(ctx context.Context) (*Table, error) { return LoadFile(path) }
+
+	rates, err := NewRates(context.Background(), load)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rates.Table().Len() != 1 || rates.Version() != 1 {
+		t.Fatalf("Expected 1 rate at version 1, got %d at version %d", rates.Table().Len(), rates.Version())
+	}
+
+	if err := os.WriteFile(path, []byte("date,base,quote,rate\n2021-11-01,USD,RUB,71.25\n2021-11-26,USD,RUB,75\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rates.Reload(context.Background()); err != nil || rates.Table().Len() != 2 || rates.Version() != 2 {
+		t.Errorf("Expected reload to pick up the new rate, got %d rates at version %d (%v)", rates.Table().Len(), rates.Version(), err)
+	}
+
+	if err := os.WriteFile(path, []byte("not,a,rate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rates.Reload(context.Background()); err == nil || rates.Table().Len() != 2 {
+		t.Errorf("Expected a failed reload to keep the previous table, got %d rates (%v)", rates.Table().Len(), err)
+	}
+
+	rub, _ := money.LookupCurrency("RUB")
+	conversion, err := rates.Table().ConvertPayment(db.Payment{Currency: "USD", Amount: 1817, PaymentDt: int(date(2021, 11, 27).Unix())}, rub)
+	if err != nil || conversion.Money.Decimal() != "136275.00" {
+		t.Errorf("Expected 136275.00 RUB, got %s (%v)", conversion.Money.Decimal(), err)
+	}
+
+	var nilRates *Rates
+	if nilRates.Table() != nil || nilRates.Version() != 0 {
+		t.Error("Expected nil rates to have no table")
+	}
+}
@@ -0,0 +1,138 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func LoadFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rates []Rate
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rates, err = ReadCSV(f)
+	case ".json":
+		rates, err = ReadJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported exchange rate file %s, expected .csv or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return NewTable(rates)
+}
+
+func ReadCSV(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	var rates []Rate
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rate, err := parseRate(record[columns["date"]], record[columns["base"]], record[columns["quote"]], record[columns["rate"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+}
+
+type jsonRate struct {
+	Date  string      `json:"date"`
+	Base  string      `json:"base"`
+	Quote string      `json:"quote"`
+	Rate  json.Number `json:"rate"`
+}
+
+func ReadJSON(r io.Reader) ([]Rate, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var entries []jsonRate
+	if err := dec.Decode(&entries); err != nil {
+		return nil, err
+	}
+	rates := make([]Rate, len(entries))
+	for i, e := range entries {
+		rate, err := parseRate(e.Date, e.Base, e.Quote, e.Rate.String())
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		rates[i] = rate
+	}
+	return rates, nil
+}
+
+func LoadDB(ctx context.Context, db *sql.DB) (*Table, error) {
+	rows, err := db.QueryContext(ctx, `SELECT rate_date, base, quote, rate::text FROM exchange_rates`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []Rate
+	for rows.Next() {
+		var date time.Time
+		var base, quote, value string
+		if err := rows.Scan(&date, &base, &quote, &value); err != nil {
+			return nil, err
+		}
+		rate, err := parseRate(date.Format(dateLayout), base, quote, value)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewTable(rates)
+}
+
+func parseRate(date, base, quote, value string) (Rate, error) {
+	d, err := time.Parse(dateLayout, strings.TrimSpace(date))
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	v, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q", value)
+	}
+	return Rate{Date: d, Base: strings.TrimSpace(base), Quote: strings.TrimSpace(quote), Value: v}, nil
+}
@@ -0,0 +1,80 @@
+package fx
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/money"
+)
+
+type LoadFunc func(ctx context.Context) (*Table, error)
+
+type Rates struct {
+	load    LoadFunc
+	table   atomic.Pointer[Table]
+	version atomic.Uint64
+}
+
+func NewRates(ctx context.Context, load LoadFunc) (*Rates, error) {
+	r := &Rates{load: load}
+	if err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Rates) Table() *Table {
+	if r == nil {
+		return nil
+	}
+	return r.table.Load()
+}
+
+func (r *Rates) Version() uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.version.Load()
+}
+
+func (r *Rates) Reload(ctx context.Context) error {
+	table, err := r.load(ctx)
+	if err != nil {
+		return err
+	}
+	r.table.Store(table)
+	r.version.Add(1)
+	return nil
+}
+
+func (r *Rates) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil {
+				log.Println("Failed to reload exchange rates, keeping the previous table:", err)
+				continue
+			}
+			log.Printf("Reloaded %d exchange rates\n", r.Table().Len())
+		}
+	}
+}
+
+func (t *Table) ConvertPayment(p db.Payment, to money.Currency) (Conversion, error) {
+	currency, err := money.LookupCurrency(p.Currency)
+	if err != nil {
+		return Conversion{}, err
+	}
+	amount, err := money.FromMajor(int64(p.Amount), currency)
+	if err != nil {
+		return Conversion{}, err
+	}
+	return t.Convert(amount, to, time.Unix(int64(p.PaymentDt), 0))
+}
@@ -0,0 +1,278 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/fx"
+	"WBTechL0/internal/money"
+	"github.com/graphql-go/graphql"
+)
+
+const maxQueryBytes = 64 << 10
+
+type OrderStore interface {
+	GetOrder(ctx context.Context, orderID string) (*db.Order, error)
+	GetDelivery(ctx context.Context, orderID string) (*db.Delivery, error)
+	GetPayment(ctx context.Context, orderID string) (*db.Payment, error)
+	GetItems(ctx context.Context, orderID string) ([]db.Item, error)
+}
+
+var (
+	errForbidden             = errors.New("forbidden")
+	errConversionUnavailable = errors.New("currency conversion is not configured")
+)
+
+func viewFromContext(ctx context.Context) auth.View {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return auth.ViewFull
+	}
+	return auth.ViewFor(principal)
+}
+
+func NewSchema(store OrderStore, rates *fx.Rates) (graphql.Schema, error) {
+	deliveryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Delivery",
+		Fields: graphql.Fields{
+			"name":    &graphql.Field{Type: graphql.String},
+			"phone":   &graphql.Field{Type: graphql.String},
+			"zip":     &graphql.Field{Type: graphql.String},
+			"city":    &graphql.Field{Type: graphql.String},
+			"address": &graphql.Field{Type: graphql.String},
+			"region":  &graphql.Field{Type: graphql.String},
+			"email":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	convertedPaymentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ConvertedPayment",
+		Fields: graphql.Fields{
+			"currency":  &graphql.Field{Type: graphql.String},
+			"amount":    &graphql.Field{Type: graphql.String},
+			"formatted": &graphql.Field{Type: graphql.String},
+			"rate":      &graphql.Field{Type: graphql.String},
+			"rate_date": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	paymentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Payment",
+		Fields: graphql.Fields{
+			"transaction":   &graphql.Field{Type: graphql.String},
+			"request_id":    &graphql.Field{Type: graphql.String},
+			"currency":      &graphql.Field{Type: graphql.String},
+			"provider":      &graphql.Field{Type: graphql.String},
+			"amount":        &graphql.Field{Type: graphql.Int},
+			"payment_dt":    &graphql.Field{Type: graphql.Int},
+			"bank":          &graphql.Field{Type: graphql.String},
+			"delivery_cost": &graphql.Field{Type: graphql.Int},
+			"goods_total":   &graphql.Field{Type: graphql.Int},
+			"custom_fee":    &graphql.Field{Type: graphql.Int},
+			"converted": &graphql.Field{
+				Type: convertedPaymentType,
+				Args: graphql.FieldConfigArgument{
+					"currency": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"locale":   &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					table := rates.Table()
+					if table == nil {
+						return nil, errConversionUnavailable
+					}
+					code := p.Args["currency"].(string)
+					to, err := money.LookupCurrency(code)
+					if err != nil {
+						return nil, fmt.Errorf("unknown currency %q", code)
+					}
+					conversion, err := table.ConvertPayment(*p.Source.(*db.Payment), to)
+					if err != nil {
+						return nil, err
+					}
+					locale, _ := p.Args["locale"].(string)
+					return map[string]any{
+						"currency":  to.Code,
+						"amount":    conversion.Money.Decimal(),
+						"formatted": conversion.Money.Format(locale),
+						"rate":      fx.FormatRate(conversion.Rate),
+						"rate_date": conversion.RateDate.Format("2006-01-02"),
+					}, nil
+				},
+			},
+		},
+	})
+
+	itemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"chrt_id":      &graphql.Field{Type: graphql.Int},
+			"track_number": &graphql.Field{Type: graphql.String},
+			"price":        &graphql.Field{Type: graphql.Int},
+			"rid":          &graphql.Field{Type: graphql.String},
+			"name":         &graphql.Field{Type: graphql.String},
+			"sale":         &graphql.Field{Type: graphql.Int},
+			"size":         &graphql.Field{Type: graphql.String},
+			"total_price":  &graphql.Field{Type: graphql.Int},
+			"nm_id":        &graphql.Field{Type: graphql.Int},
+			"brand":        &graphql.Field{Type: graphql.String},
+			"status":       &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	orderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"order_uid":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"track_number":       &graphql.Field{Type: graphql.String},
+			"entry":              &graphql.Field{Type: graphql.String},
+			"locale":             &graphql.Field{Type: graphql.String},
+			"internal_signature": &graphql.Field{Type: graphql.String},
+			"customer_id":        &graphql.Field{Type: graphql.String},
+			"delivery_service":   &graphql.Field{Type: graphql.String},
+			"shardkey":           &graphql.Field{Type: graphql.String},
+			"sm_id":              &graphql.Field{Type: graphql.Int},
+			"oof_shard":          &graphql.Field{Type: graphql.String},
+			"date_created": &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"tz": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					loc := time.UTC
+					if name, ok := p.Args["tz"].(string); ok && name != "" {
+						var err error
+						if loc, err = time.LoadLocation(name); err != nil {
+							return nil, fmt.Errorf("unknown time zone %q", name)
+						}
+					}
+					return p.Source.(*db.Order).DateCreated.In(loc).Format(time.RFC3339), nil
+				},
+			},
+			"delivery": &graphql.Field{
+				Type: deliveryType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					delivery, err := store.GetDelivery(p.Context, p.Source.(*db.Order).OrderUID)
+					if errors.Is(err, sql.ErrNoRows) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					if viewFromContext(p.Context) != auth.ViewFull {
+						masked := auth.MaskDelivery(*delivery)
+						return &masked, nil
+					}
+					return delivery, nil
+				},
+			},
+			"payment": &graphql.Field{
+				Type: paymentType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					view := viewFromContext(p.Context)
+					if view != auth.ViewFull && view != auth.ViewFinance {
+						return nil, errForbidden
+					}
+					payment, err := store.GetPayment(p.Context, p.Source.(*db.Order).OrderUID)
+					if errors.Is(err, sql.ErrNoRows) {
+						return nil, nil
+					}
+					return payment, err
+				},
+			},
+			"items": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(itemType)),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return store.GetItems(p.Context, p.Source.(*db.Order).OrderUID)
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"order": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"order_uid": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if viewFromContext(p.Context) == auth.ViewNone {
+						return nil, errForbidden
+					}
+					order, err := store.GetOrder(p.Context, p.Args["order_uid"].(string))
+					if errors.Is(err, sql.ErrNoRows) {
+						return nil, nil
+					}
+					return order, err
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func NewHandler(store OrderStore, rates *fx.Rates) (http.Handler, error) {
+	schema, err := NewSchema(store, rates)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		switch r.Method {
+		case http.MethodGet:
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if variables := r.URL.Query().Get("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+					http.Error(w, "Invalid variables", http.StatusBadRequest)
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQueryBytes)).Decode(&req); err != nil {
+				http.Error(w, "Invalid GraphQL request", http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if req.Query == "" {
+			http.Error(w, "Query is required", http.StatusBadRequest)
+			return
+		}
+
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        r.Context(),
+		})
+		if result.HasErrors() {
+			log.Println("GraphQL query returned errors:", result.Errors)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Println("Failed to encode GraphQL response:", err)
+		}
+	}), nil
+}
@@ -0,0 +1,156 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/fx"
+)
+
+type fakeStore struct {
+	calls map[string]int
+}
+
+func (s *fakeStore) GetOrder(ctx context.Context, orderID string) (*db.Order, error) {
+	s.calls["order"]++
+	if orderID != "b563feb7b2b84b6test" {
+		return nil, sql.ErrNoRows
+	}
+	return &db.Order{OrderUID: orderID, TrackNumber: "WBILMTESTTRACK", DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)}, nil
+}
+
+func (s *fakeStore) GetDelivery(ctx context.Context, orderID string) (*db.Delivery, error) {
+	s.calls["delivery"]++
+	return &db.Delivery{OrderUID: orderID, Name: "Test Testov", City: "Kiryat Mozkin"}, nil
+}
+
+func (s *fakeStore) GetPayment(ctx context.Context, orderID string) (*db.Payment, error) {
+	s.calls["payment"]++
+	return &db.Payment{OrderUID: orderID, Currency: "USD", Amount: 1817, PaymentDt: 1637907727}, nil
+}
+
+func (s *fakeStore) GetItems(ctx context.Context, orderID string) ([]db.Item, error) {
+	s.calls["items"]++
+	return []db.Item{{OrderUID: orderID, Name: "Mascaras", Status: 202}}, nil
+}
+
+func query(t *testing.T, handler http.Handler, q string, principal *auth.Principal) map[string]any {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	if principal != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %v, got %v", http.StatusOK, rr.Code)
+	}
+	var result map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return result
+}
+
+func TestSelectiveResolution(t *testing.T) {
+	store := &fakeStore{calls: make(map[string]int)}
+	handler, err := NewHandler(store, nil)
+	if err != nil {
+		t.Fatalf("Failed to build handler: %v", err)
+	}
+
+	result := query(t, handler, `{ order(order_uid: "b563feb7b2b84b6test") { track_number payment { amount currency } } }`, nil)
+	order := result["data"].(map[string]any)["order"].(map[string]any)
+	if order["track_number"] != "WBILMTESTTRACK" || order["payment"].(map[string]any)["amount"] != float64(1817) {
+		t.Errorf("Unexpected order: %v", order)
+	}
+	if store.calls["items"] != 0 || store.calls["delivery"] != 0 {
+		t.Errorf("Expected items and delivery not to be fetched, got calls %v", store.calls)
+	}
+
+	result = query(t, handler, `{ order(order_uid: "missing") { order_uid } }`, nil)
+	if result["data"].(map[string]any)["order"] != nil {
+		t.Errorf("Expected missing order to resolve to null, got %v", result)
+	}
+}
+
+func TestRoleRestrictions(t *testing.T) {
+	store := &fakeStore{calls: make(map[string]int)}
+	handler, err := NewHandler(store, nil)
+	if err != nil {
+		t.Fatalf("Failed to build handler: %v", err)
+	}
+
+	support := &auth.Principal{Subject: "agent", Roles: []auth.Role{auth.RoleSupport}}
+	result := query(t, handler, `{ order(order_uid: "b563feb7b2b84b6test") { delivery { name } payment { amount } } }`, support)
+
+	order := result["data"].(map[string]any)["order"].(map[string]any)
+	if order["delivery"].(map[string]any)["name"] != "T*** T*****" {
+		t.Errorf("Expected masked delivery name, got %v", order["delivery"])
+	}
+	if order["payment"] != nil || result["errors"] == nil {
+		t.Errorf("Expected payment to be denied for support role, got %v", result)
+	}
+	if store.calls["payment"] != 0 {
+		t.Errorf("Expected payment not to be fetched for support role, got %d calls", store.calls["payment"])
+	}
+}
+
+func TestDateCreatedTimeZone(t *testing.T) {
+	handler, err := NewHandler(&fakeStore{calls: make(map[string]int)}, nil)
+	if err != nil {
+		t.Fatalf("Failed to build handler: %v", err)
+	}
+
+	result := query(t, handler, `{ order(order_uid: "b563feb7b2b84b6test") { utc: date_created msk: date_created(tz: "Europe/Moscow") } }`, nil)
+	order := result["data"].(map[string]any)["order"].(map[string]any)
+	if order["utc"] != "2021-11-26T06:22:19Z" || order["msk"] != "2021-11-26T09:22:19+03:00" {
+		t.Errorf("Unexpected date_created values: %v", order)
+	}
+
+	result = query(t, handler, `{ order(order_uid: "b563feb7b2b84b6test") { date_created(tz: "Mars/Olympus") } }`, nil)
+	if result["errors"] == nil {
+		t.Errorf("Expected an error for an unknown time zone, got %v", result)
+	}
+}
+
+func TestPaymentConversion(t *testing.T) {
+	table, err := fx.NewTable([]fx.Rate{{Date: time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC), Base: "USD", Quote: "RUB", Value: big.NewRat(7125, 100)}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	rates, err := fx.NewRates(context.Background(), func(ctx context.Context) (*fx.Table, error) { return table, nil })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler, err := NewHandler(&fakeStore{calls: make(map[string]int)}, rates)
+	if err != nil {
+		t.Fatalf("Failed to build handler: %v", err)
+	}
+
+	result := query(t, handler, `{ order(order_uid: "b563feb7b2b84b6test") { payment { converted(currency: "RUB") { amount rate rate_date } } } }`, nil)
+	payment := result["data"].(map[string]any)["order"].(map[string]any)["payment"].(map[string]any)
+	converted, _ := payment["converted"].(map[string]any)
+	if converted["amount"] != "129461.25" || converted["rate"] != "71.25" || converted["rate_date"] != "2021-11-01" {
+		t.Errorf("Unexpected conversion: %v (%v)", converted, result["errors"])
+	}
+
+	noRates, err := NewHandler(&fakeStore{calls: make(map[string]int)}, nil)
+	if err != nil {
+		t.Fatalf("Failed to build handler: %v", err)
+	}
+	result = query(t, noRates, `{ order(order_uid: "b563feb7b2b84b6test") { payment { converted(currency: "RUB") { amount } } } }`, nil)
+	if result["errors"] == nil {
+		t.Errorf("Expected an error without exchange rates, got %v", result)
+	}
+}
@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"WBTechL0/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func unauthenticated(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+func authenticate(ctx context.Context, authenticator auth.Authenticator, fullMethod string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: make(http.Header, len(md))}
+	for key, values := range md {
+		for _, v := range values {
+			r.Header.Add(key, v)
+		}
+	}
+	principal, err := authenticator.Authenticate(r)
+	if err != nil {
+		log.Printf("Authentication failed for gRPC %s: %v\n", fullMethod, err)
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func unaryAuth(authenticator auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if unauthenticated(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func streamAuth(authenticator auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if unauthenticated(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func viewFor(ctx context.Context) (auth.View, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return auth.ViewFull, nil
+	}
+	view := auth.ViewFor(principal)
+	if view == auth.ViewNone {
+		return view, status.Error(codes.PermissionDenied, "forbidden")
+	}
+	return view, nil
+}
@@ -0,0 +1,333 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/cache"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+	"WBTechL0/internal/fx"
+	"WBTechL0/internal/grpc/orderspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	maxBatchSize    = 1000
+)
+
+type Server struct {
+	orderspb.UnimplementedOrderServiceServer
+	cache      *cache.Cache
+	db         *sql.DB
+	broker     *events.Broker
+	rates      *fx.Rates
+	grpcServer *grpc.Server
+	health     *health.Server
+}
+
+type Option func(*options)
+
+type options struct {
+	authenticator auth.Authenticator
+	rates         *fx.Rates
+}
+
+func WithAuthenticator(authenticator auth.Authenticator) Option {
+	return func(o *options) {
+		o.authenticator = authenticator
+	}
+}
+
+func WithExchangeRates(rates *fx.Rates) Option {
+	return func(o *options) {
+		o.rates = rates
+	}
+}
+
+func NewServer(orderCache *cache.Cache, database *sql.DB, broker *events.Broker, opts ...Option) *Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var serverOpts []grpc.ServerOption
+	if o.authenticator != nil {
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(unaryAuth(o.authenticator)),
+			grpc.ChainStreamInterceptor(streamAuth(o.authenticator)),
+		)
+	}
+
+	s := &Server{
+		cache:      orderCache,
+		db:         database,
+		broker:     broker,
+		rates:      o.rates,
+		grpcServer: grpc.NewServer(serverOpts...),
+		health:     health.NewServer(),
+	}
+	orderspb.RegisterOrderServiceServer(s.grpcServer, s)
+	healthpb.RegisterHealthServer(s.grpcServer, s.health)
+	reflection.Register(s.grpcServer)
+
+	s.health.SetServingStatus(orderspb.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s
+}
+
+func (s *Server) Serve(port string) error {
+	lis, err := net.Listen("tcp", "0.0.0.0:"+port)
+	if err != nil {
+		return err
+	}
+	log.Println("Starting gRPC server on", lis.Addr())
+	return s.grpcServer.Serve(lis)
+}
+
+func (s *Server) GracefulStop() {
+	s.health.Shutdown()
+	s.grpcServer.GracefulStop()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (s *Server) GetOrder(ctx context.Context, req *orderspb.GetOrderRequest) (*orderspb.Order, error) {
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+	r, err := s.renderer(ctx, req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.loadOrder(ctx, r, req.GetOrderUid())
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *Server) ListOrders(ctx context.Context, req *orderspb.ListOrdersRequest) (*orderspb.ListOrdersResponse, error) {
+	r, err := s.renderer(ctx, req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize <= 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	filter := db.OrderFilter{
+		CustomerID:      req.GetCustomerId(),
+		DeliveryService: req.GetDeliveryService(),
+		Limit:           pageSize + 1,
+	}
+	if req.GetCreatedFrom() != nil {
+		filter.CreatedFrom = req.GetCreatedFrom().AsTime()
+	}
+	if req.GetCreatedTo() != nil {
+		filter.CreatedTo = req.GetCreatedTo().AsTime()
+	}
+	if req.GetPageToken() != "" {
+		after, err := base64.RawURLEncoding.DecodeString(req.GetPageToken())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		filter.AfterUID = string(after)
+	}
+
+	orders, err := db.ListOrders(ctx, s.db, filter)
+	if err != nil {
+		return nil, internalError(err, "failed to list orders")
+	}
+
+	resp := &orderspb.ListOrdersResponse{}
+	if len(orders) > pageSize {
+		orders = orders[:pageSize]
+		resp.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(orders[len(orders)-1].OrderUID))
+	}
+	orderUIDs := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderUIDs = append(orderUIDs, order.OrderUID)
+	}
+	aggregates, _, err := s.cache.GetOrdersBatch(ctx, orderUIDs)
+	if err != nil {
+		return nil, internalError(err, "failed to load orders")
+	}
+	for i := range aggregates {
+		aggregate := &aggregates[i]
+		resp.Orders = append(resp.Orders, r.proto(&aggregate.Order, aggregate.Delivery, aggregate.Payment, aggregate.Items))
+	}
+	return resp, nil
+}
+
+func (s *Server) BatchGetOrders(ctx context.Context, req *orderspb.BatchGetOrdersRequest) (*orderspb.BatchGetOrdersResponse, error) {
+	if len(req.GetOrderUids()) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d order_uids are allowed", maxBatchSize)
+	}
+	r, err := s.renderer(ctx, req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates, missing, err := s.cache.GetOrdersBatch(ctx, req.GetOrderUids())
+	if err != nil {
+		return nil, internalError(err, "failed to load orders")
+	}
+
+	resp := &orderspb.BatchGetOrdersResponse{MissingOrderUids: missing}
+	for i := range aggregates {
+		aggregate := &aggregates[i]
+		resp.Orders = append(resp.Orders, r.proto(&aggregate.Order, aggregate.Delivery, aggregate.Payment, aggregate.Items))
+	}
+	return resp, nil
+}
+
+func (s *Server) WatchOrders(req *orderspb.WatchOrdersRequest, stream orderspb.OrderService_WatchOrdersServer) error {
+	if s.broker == nil {
+		return status.Error(codes.Unavailable, "order events are not available")
+	}
+	r, err := s.renderer(stream.Context(), req.GetCurrency())
+	if err != nil {
+		return err
+	}
+
+	sub, _ := s.broker.Subscribe(events.Filter{
+		DeliveryService: req.GetDeliveryService(),
+		CustomerID:      req.GetCustomerId(),
+	}, 0)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case evt, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client fell behind the order stream")
+			}
+			if err := stream.Send(r.proto(&evt.Order, &evt.Delivery, &evt.Payment, evt.Items)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) loadOrder(ctx context.Context, r renderer, orderUID string) (*orderspb.Order, error) {
+	order, err := s.cache.GetOrder(ctx, orderUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "order %s not found", orderUID)
+	}
+	if err != nil {
+		return nil, internalError(err, "failed to load order")
+	}
+	delivery, err := s.cache.GetDelivery(ctx, orderUID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, internalError(err, "failed to load delivery")
+	}
+	payment, err := s.cache.GetPayment(ctx, orderUID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, internalError(err, "failed to load payment")
+	}
+	items, err := s.cache.GetItems(ctx, orderUID)
+	if err != nil {
+		return nil, internalError(err, "failed to load items")
+	}
+	return r.proto(order, delivery, payment, items), nil
+}
+
+func internalError(err error, msg string) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, msg)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, msg)
+	}
+	return status.Error(codes.Internal, msg)
+}
+
+func ToProto(order *db.Order, delivery *db.Delivery, payment *db.Payment, items []db.Item) *orderspb.Order {
+	pbOrder := &orderspb.Order{
+		OrderUid:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerId:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.ShardKey,
+		SmId:              int64(order.SMID),
+		DateCreated:       timestamppb.New(order.DateCreated),
+		OofShard:          order.OOFShard,
+	}
+	if delivery != nil {
+		pbOrder.Delivery = &orderspb.Delivery{
+			Name:    delivery.Name,
+			Phone:   delivery.Phone,
+			Zip:     delivery.Zip,
+			City:    delivery.City,
+			Address: delivery.Address,
+			Region:  delivery.Region,
+			Email:   delivery.Email,
+		}
+	}
+	if payment != nil {
+		pbOrder.Payment = &orderspb.Payment{
+			Transaction:  payment.Transaction,
+			RequestId:    payment.RequestID,
+			Currency:     payment.Currency,
+			Provider:     payment.Provider,
+			Amount:       int64(payment.Amount),
+			PaymentDt:    int64(payment.PaymentDt),
+			Bank:         payment.Bank,
+			DeliveryCost: int64(payment.DeliveryCost),
+			GoodsTotal:   int64(payment.GoodsTotal),
+			CustomFee:    int64(payment.CustomFee),
+		}
+	}
+	for _, item := range items {
+		pbOrder.Items = append(pbOrder.Items, &orderspb.Item{
+			ChrtId:      int64(item.ChrtID),
+			TrackNumber: item.TrackNumber,
+			Price:       int64(item.Price),
+			Rid:         item.RID,
+			Name:        item.Name,
+			Sale:        int64(item.Sale),
+			Size:        item.Size,
+			TotalPrice:  int64(item.TotalPrice),
+			NmId:        int64(item.NMID),
+			Brand:       item.Brand,
+			Status:      int64(item.Status),
+		})
+	}
+	return pbOrder
+}
@@ -0,0 +1,223 @@
+package grpc
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+	"WBTechL0/internal/fx"
+	"WBTechL0/internal/grpc/orderspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dialServer(t *testing.T, server *Server) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	go server.grpcServer.Serve(lis)
+	t.Cleanup(server.GracefulStop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestWatchOrders(t *testing.T) {
+	broker := events.NewBroker(10)
+	conn := dialServer(t, NewServer(nil, nil, broker))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "orders.v1.OrderService"})
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected order service to be serving, got %v (%v)", health.GetStatus(), err)
+	}
+
+	client := orderspb.NewOrderServiceClient(conn)
+	if _, err := client.GetOrder(ctx, &orderspb.GetOrderRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for empty order_uid, got %v", err)
+	}
+
+	stream, err := client.WatchOrders(ctx, &orderspb.WatchOrdersRequest{DeliveryService: "meest"})
+	if err != nil {
+		t.Fatalf("Failed to watch orders: %v", err)
+	}
+	for broker.Subscribers() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "skipped", DeliveryService: "dhl"}})
+	broker.Publish(events.OrderEvent{
+		Order:   db.Order{OrderUID: "b563feb7b2b84b6test", DeliveryService: "meest", DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)},
+		Payment: db.Payment{Currency: "USD", Amount: 1817},
+		Items:   []db.Item{{ChrtID: 9934930, Name: "Mascaras"}},
+	})
+
+	order, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive order: %v", err)
+	}
+	if order.GetOrderUid() != "b563feb7b2b84b6test" || order.GetPayment().GetAmount() != 1817 || len(order.GetItems()) != 1 {
+		t.Errorf("Unexpected order: %v", order)
+	}
+	if !order.GetDateCreated().AsTime().Equal(time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)) {
+		t.Errorf("Unexpected date_created: %v", order.GetDateCreated().AsTime())
+	}
+}
+
+func TestWatchOrdersCurrency(t *testing.T) {
+	table, err := fx.NewTable([]fx.Rate{{Date: time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC), Base: "USD", Quote: "RUB", Value: big.NewRat(7125, 100)}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	rates, err := fx.NewRates(context.Background(), func(ctx context.Context) (*fx.Table, error) { return table, nil })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	broker := events.NewBroker(10)
+	client := orderspb.NewOrderServiceClient(dialServer(t, NewServer(nil, nil, broker, WithExchangeRates(rates))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchOrders(ctx, &orderspb.WatchOrdersRequest{Currency: "ZZZ"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown currency, got %v", err)
+	}
+
+	stream, err = client.WatchOrders(ctx, &orderspb.WatchOrdersRequest{Currency: "rub"})
+	if err != nil {
+		t.Fatalf("Failed to watch orders: %v", err)
+	}
+	for broker.Subscribers() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	paidAt := int(time.Date(2021, 11, 26, 0, 0, 0, 0, time.UTC).Unix())
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "a"}, Payment: db.Payment{Currency: "USD", Amount: 1817, PaymentDt: paidAt}})
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "b"}, Payment: db.Payment{Currency: "USD", Amount: 1817, PaymentDt: 1}})
+
+	order, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive order: %v", err)
+	}
+	converted := order.GetPayment().GetConverted()
+	if converted.GetCurrency() != "RUB" || converted.GetAmount() != "129461.25" || converted.GetRate() != "71.25" || converted.GetRateDate() != "2021-11-01" {
+		t.Errorf("Unexpected conversion: %v", converted)
+	}
+
+	order, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive order: %v", err)
+	}
+	if order.GetPayment().GetConverted() != nil || order.GetPayment().GetConversionError() == "" {
+		t.Errorf("Expected a conversion error for a payment before the first rate, got %v", order.GetPayment())
+	}
+
+	noRates := orderspb.NewOrderServiceClient(dialServer(t, NewServer(nil, nil, events.NewBroker(10))))
+	stream, err = noRates.WatchOrders(ctx, &orderspb.WatchOrdersRequest{Currency: "RUB"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected Unimplemented without exchange rates, got %v", err)
+	}
+}
+
+func TestWatchOrdersAuth(t *testing.T) {
+	broker := events.NewBroker(10)
+	authenticator := auth.NewAPIKeyAuthenticator([]auth.APIKey{
+		{Key: "support-key", Subject: "support", Roles: []string{"support"}},
+		{Key: "nobody-key", Subject: "nobody"},
+	})
+	conn := dialServer(t, NewServer(nil, nil, broker, WithAuthenticator(authenticator)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected health check without credentials to pass, got %v (%v)", health.GetStatus(), err)
+	}
+
+	client := orderspb.NewOrderServiceClient(conn)
+	if _, err := client.GetOrder(ctx, &orderspb.GetOrderRequest{OrderUid: "b563feb7b2b84b6test"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without credentials, got %v", err)
+	}
+	invalid := metadata.AppendToOutgoingContext(ctx, "x-api-key", "wrong")
+	if _, err := client.ListOrders(invalid, &orderspb.ListOrdersRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for an invalid key, got %v", err)
+	}
+	nobody := metadata.AppendToOutgoingContext(ctx, "x-api-key", "nobody-key")
+	if _, err := client.GetOrder(nobody, &orderspb.GetOrderRequest{OrderUid: "b563feb7b2b84b6test"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied without roles, got %v", err)
+	}
+
+	support := metadata.AppendToOutgoingContext(ctx, "authorization", "ApiKey support-key")
+	stream, err := client.WatchOrders(support, &orderspb.WatchOrdersRequest{})
+	if err != nil {
+		t.Fatalf("Failed to watch orders: %v", err)
+	}
+	for broker.Subscribers() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	broker.Publish(events.OrderEvent{
+		Order:    db.Order{OrderUID: "b563feb7b2b84b6test"},
+		Delivery: db.Delivery{Name: "Test Testov", Phone: "+9720000000", Email: "test@gmail.com"},
+		Payment:  db.Payment{Currency: "USD", Amount: 1817},
+	})
+
+	order, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive order: %v", err)
+	}
+	if order.GetPayment() != nil {
+		t.Errorf("Expected payment to be hidden from support, got %v", order.GetPayment())
+	}
+	if order.GetDelivery().GetPhone() == "+9720000000" || order.GetDelivery().GetEmail() == "test@gmail.com" {
+		t.Errorf("Expected delivery to be masked for support, got %v", order.GetDelivery())
+	}
+}
+
+func TestShutdownStopsOpenStreams(t *testing.T) {
+	broker := events.NewBroker(10)
+	server := NewServer(nil, nil, broker)
+	conn := dialServer(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := orderspb.NewOrderServiceClient(conn).WatchOrders(ctx, &orderspb.WatchOrdersRequest{})
+	if err != nil {
+		t.Fatalf("Failed to watch orders: %v", err)
+	}
+	for broker.Subscribers() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Expected the stream to be closed after shutdown")
+	}
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"context"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/fx"
+	"WBTechL0/internal/grpc/orderspb"
+	"WBTechL0/internal/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type renderer struct {
+	view     auth.View
+	currency *money.Currency
+	rates    *fx.Table
+}
+
+func (s *Server) renderer(ctx context.Context, currency string) (renderer, error) {
+	view, err := viewFor(ctx)
+	if err != nil {
+		return renderer{}, err
+	}
+	r := renderer{view: view}
+	if currency == "" {
+		return r, nil
+	}
+	if r.rates = s.rates.Table(); r.rates == nil {
+		return renderer{}, status.Error(codes.Unimplemented, "currency conversion is not configured")
+	}
+	to, err := money.LookupCurrency(currency)
+	if err != nil {
+		return renderer{}, status.Errorf(codes.InvalidArgument, "unknown currency %q", currency)
+	}
+	r.currency = &to
+	return r, nil
+}
+
+func (r renderer) proto(order *db.Order, delivery *db.Delivery, payment *db.Payment, items []db.Item) *orderspb.Order {
+	delivery, payment = auth.Restrict(r.view, delivery, payment)
+	pbOrder := ToProto(order, delivery, payment, items)
+	if payment == nil || r.currency == nil {
+		return pbOrder
+	}
+	conversion, err := r.rates.ConvertPayment(*payment, *r.currency)
+	if err != nil {
+		pbOrder.Payment.ConversionError = err.Error()
+		return pbOrder
+	}
+	pbOrder.Payment.Converted = &orderspb.ConvertedPayment{
+		Currency: r.currency.Code,
+		Amount:   conversion.Money.Decimal(),
+		Rate:     fx.FormatRate(conversion.Rate),
+		RateDate: conversion.RateDate.Format("2006-01-02"),
+	}
+	return pbOrder
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/cache"
+	"WBTechL0/internal/fx"
+)
+
+type batchGetRequest struct {
+	OrderUIDs []string `json:"order_uids"`
+}
+
+type batchGetResponse struct {
+	Orders  []orderResponse `json:"orders"`
+	Missing []string        `json:"missing"`
+}
+
+func batchGetHandler(orderCache *cache.Cache, rates *fx.Rates, maxIDs int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := responseView(r)
+		if view == auth.ViewNone {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		opts, err := parseRenderOptions(r, rates)
+		if err != nil {
+			renderOptionsFailed(w, err)
+			return
+		}
+
+		var req batchGetRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(maxIDs)*256+1024)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.OrderUIDs) == 0 {
+			http.Error(w, "order_uids is required", http.StatusBadRequest)
+			return
+		}
+		if len(req.OrderUIDs) > maxIDs {
+			http.Error(w, fmt.Sprintf("At most %d order_uids are allowed", maxIDs), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		aggregates, missing, err := orderCache.GetOrdersBatch(r.Context(), req.OrderUIDs)
+		if errors.Is(err, context.DeadlineExceeded) {
+			http.Error(w, "Timed out fetching orders", http.StatusGatewayTimeout)
+			log.Println("Timed out fetching order batch:", err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+			log.Println("Failed to fetch order batch:", err)
+			return
+		}
+
+		resp := batchGetResponse{
+			Orders:  make([]orderResponse, 0, len(aggregates)),
+			Missing: missing,
+		}
+		if resp.Missing == nil {
+			resp.Missing = []string{}
+		}
+		for i := range aggregates {
+			aggregate := &aggregates[i]
+			rendered, err := restrictOrder(view, orderResponse{
+				Order:    &aggregate.Order,
+				Delivery: aggregate.Delivery,
+				Payment:  aggregate.Payment,
+				Items:    aggregate.Items,
+			}).render(opts)
+			if err != nil {
+				log.Println("Returning batch order without conversion:", err)
+			}
+			resp.Orders = append(resp.Orders, rendered)
+		}
+		log.Printf("Batch lookup returned %d orders, %d missing\n", len(resp.Orders), len(resp.Missing))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Println("Failed to encode batch response:", err)
+		}
+	}
+}
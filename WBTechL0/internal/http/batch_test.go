@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/fx"
+)
+
+func TestBatchGetValidation(t *testing.T) {
+	cfg := config.Load().HTTP
+	cfg.BatchMaxIDs = 2
+	handler := NewServer(nil, cfg).Handler()
+
+	cases := map[string]int{
+		`not json`:                       http.StatusBadRequest,
+		`{"order_uids":[]}`:              http.StatusBadRequest,
+		`{"order_uids":["a","b","c"]}`:   http.StatusRequestEntityTooLarge,
+		`{"order_uids":["a","b","c","d"`: http.StatusBadRequest,
+	}
+	for body, expected := range cases {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/orders:batchGet", strings.NewReader(body)))
+		if rr.Code != expected {
+			t.Errorf("Body %q: expected status code %v, got %v", body, expected, rr.Code)
+		}
+	}
+}
+
+func TestRenderOptions(t *testing.T) {
+	cases := map[string]string{
+		"":                 "UTC",
+		"?tz=UTC":          "UTC",
+		"?tz=Europe/Paris": "Europe/Paris",
+	}
+	for query, expected := range cases {
+		opts, err := parseRenderOptions(httptest.NewRequest(http.MethodGet, "/order/1"+query, nil), nil)
+		if err != nil || opts.loc.String() != expected {
+			t.Errorf("Query %q: expected location %s, got %v (%v)", query, expected, opts.loc, err)
+		}
+	}
+
+	variants := make(map[string]bool)
+	for _, query := range []string{"?locale=ru", "?locale=ru-RU", "?locale=ru_ru", "?locale=xx", "?locale=yy", "?locale=en"} {
+		opts, err := parseRenderOptions(httptest.NewRequest(http.MethodGet, "/order/1"+query, nil), nil)
+		if err != nil {
+			t.Fatalf("Query %q: expected no error, got %v", query, err)
+		}
+		variants[opts.variant(auth.ViewFull)] = true
+	}
+	if len(variants) != 2 {
+		t.Errorf("Expected locales to collapse into 2 cache variants, got %v", variants)
+	}
+
+	variants = make(map[string]bool)
+	for _, query := range []string{"?tz=Europe/Paris", "?tz=Europe//Paris", "?tz=Europe/./Paris", "?tz=./Europe/Paris"} {
+		opts, err := parseRenderOptions(httptest.NewRequest(http.MethodGet, "/order/1"+query, nil), nil)
+		if err != nil {
+			t.Fatalf("Query %q: expected no error, got %v", query, err)
+		}
+		variants[opts.variant(auth.ViewFull)] = true
+	}
+	if len(variants) != 1 {
+		t.Errorf("Expected time zone spellings to collapse into 1 cache variant, got %v", variants)
+	}
+
+	if _, err := parseRenderOptions(httptest.NewRequest(http.MethodGet, "/order/1?tz=Mars/Olympus", nil), nil); err == nil {
+		t.Error("Expected an error for an unknown time zone")
+	}
+
+	created := time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)
+	order := &db.Order{OrderUID: "a", Locale: "en", DateCreated: created}
+	payment := &db.Payment{Currency: "USD", Amount: 1817, DeliveryCost: 1500, GoodsTotal: 317}
+	loc, _ := time.LoadLocation("Europe/Moscow")
+	resp, err := orderResponse{Order: order, Payment: payment}.render(renderOptions{loc: loc})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := resp.Order.DateCreated.Format(time.RFC3339); got != "2021-11-26T09:22:19+03:00" {
+		t.Errorf("Expected date_created in Moscow time, got %s", got)
+	}
+	if order.DateCreated.Location() != time.UTC {
+		t.Error("Expected the cached order to be left untouched")
+	}
+	if resp.PaymentFormatted == nil || resp.PaymentFormatted.Amount != "$1,817.00" || resp.PaymentFormatted.CustomFee != "$0.00" {
+		t.Errorf("Expected payment formatted for the order locale, got %+v", resp.PaymentFormatted)
+	}
+
+	resp, _ = orderResponse{Order: order, Payment: payment}.render(renderOptions{loc: time.UTC, locale: "ru"})
+	if resp.PaymentFormatted == nil || resp.PaymentFormatted.Amount != "1\u00a0817,00\u00a0$" {
+		t.Errorf("Expected payment formatted for the requested locale, got %+v", resp.PaymentFormatted)
+	}
+
+	payment = &db.Payment{Currency: "XXX", Amount: 1}
+	if resp, _ := (orderResponse{Order: order, Payment: payment}).render(renderOptions{loc: time.UTC}); resp.PaymentFormatted != nil {
+		t.Errorf("Expected no formatting for an unknown currency, got %+v", resp.PaymentFormatted)
+	}
+}
+
+func TestCurrencyConversion(t *testing.T) {
+	table, err := fx.NewTable([]fx.Rate{
+		{Date: time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC), Base: "USD", Quote: "RUB", Value: big.NewRat(7125, 100)},
+		{Date: time.Date(2021, 11, 26, 0, 0, 0, 0, time.UTC), Base: "USD", Quote: "RUB", Value: big.NewRat(7500, 100)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	rates, err := fx.NewRates(context.Background(), func(ctx context.Context) (*fx.Table, error) { return table, nil })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cases := map[string]int{
+		"?currency=RUB":       http.StatusOK,
+		"?currency=usd":       http.StatusOK,
+		"?currency=ZZZ":       http.StatusBadRequest,
+		"?currency=RUB&tz=UT": http.StatusBadRequest,
+	}
+	for query, expected := range cases {
+		rr := httptest.NewRecorder()
+		if _, err := parseRenderOptions(httptest.NewRequest(http.MethodGet, "/order/1"+query, nil), rates); err != nil {
+			renderOptionsFailed(rr, err)
+		}
+		if rr.Code != expected {
+			t.Errorf("Query %q: expected status code %v, got %v", query, expected, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	_, err = parseRenderOptions(httptest.NewRequest(http.MethodGet, "/order/1?currency=RUB", nil), nil)
+	renderOptionsFailed(rr, err)
+	if rr.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status code %v without rates, got %v", http.StatusNotImplemented, rr.Code)
+	}
+
+	opts, err := parseRenderOptions(httptest.NewRequest(http.MethodGet, "/order/1?currency=RUB&locale=ru", nil), rates)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	order := &db.Order{OrderUID: "a", Locale: "en"}
+	paidAt := time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC)
+	payment := &db.Payment{OrderUID: "a", Currency: "USD", Amount: 1817, PaymentDt: int(paidAt.Unix())}
+	resp, err := orderResponse{Order: order, Payment: payment}.render(opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	converted := resp.PaymentConverted
+	if converted == nil || converted.Amount != "129461.25" || converted.Rate != "71.25" || converted.RateDate != "2021-11-01" || converted.Formatted != "129\u00a0461,25\u00a0₽" {
+		t.Errorf("Unexpected conversion: %+v", converted)
+	}
+
+	payment = &db.Payment{OrderUID: "a", Currency: "USD", Amount: 1817, PaymentDt: int(time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC).Unix())}
+	resp, err = orderResponse{Order: order, Payment: payment}.render(opts)
+	if !errors.Is(err, fx.ErrNoRate) {
+		t.Errorf("Expected an error for a payment before the first rate, got %v", err)
+	}
+	if resp.Payment == nil || resp.PaymentConverted != nil || resp.ConversionError == "" {
+		t.Errorf("Expected the order to be returned with a conversion error instead of a conversion, got %+v", resp)
+	}
+}
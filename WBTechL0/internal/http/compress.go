@@ -0,0 +1,250 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+const minCompressSize = 1024
+
+const (
+	encodingBrotli   = "br"
+	encodingGzip     = "gzip"
+	encodingIdentity = ""
+)
+
+var gzipPool = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+var brotliPool = sync.Pool{
+	New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	},
+}
+
+func negotiateEncoding(header string) string {
+	best := encodingIdentity
+	bestQ := 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		var candidate string
+		switch name {
+		case encodingBrotli:
+			candidate = encodingBrotli
+		case encodingGzip, "x-gzip":
+			candidate = encodingGzip
+		case "*":
+			candidate = encodingBrotli
+		default:
+			continue
+		}
+		if q > bestQ || (q == bestQ && candidate == encodingBrotli) {
+			best = candidate
+			bestQ = q
+		}
+	}
+	return best
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case encodingBrotli:
+		bw := brotliPool.Get().(*brotli.Writer)
+		bw.Reset(w)
+		return &pooledEncoder{WriteCloser: bw, release: func() { brotliPool.Put(bw) }}
+	case encodingGzip:
+		gw := gzipPool.Get().(*gzip.Writer)
+		gw.Reset(w)
+		return &pooledEncoder{WriteCloser: gw, release: func() { gzipPool.Put(gw) }}
+	}
+	return nil
+}
+
+type pooledEncoder struct {
+	io.WriteCloser
+	release func()
+}
+
+func (e *pooledEncoder) Flush() error {
+	if f, ok := e.WriteCloser.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (e *pooledEncoder) Close() error {
+	err := e.WriteCloser.Close()
+	e.release()
+	return err
+}
+
+func compressBytes(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := newEncoder(encoding, &buf)
+	if enc == nil {
+		return body, nil
+	}
+	if _, err := enc.Write(body); err != nil {
+		enc.Close()
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func isCompressible(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "json"), strings.HasSuffix(mediaType, "xml"), strings.HasSuffix(mediaType, "javascript"):
+		return true
+	}
+	return false
+}
+
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == encodingIdentity || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	buf         []byte
+	enc         io.WriteCloser
+	decided     bool
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= minCompressSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	h := cw.Header()
+
+	if compress && h.Get("Content-Encoding") == "" && isCompressible(h.Get("Content-Type")) &&
+		cw.status != http.StatusNoContent && cw.status != http.StatusNotModified {
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.enc = newEncoder(cw.encoding, cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buffered := cw.buf
+	cw.buf = nil
+	if len(buffered) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buffered)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buffered)
+	return err
+}
+
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		if f, ok := cw.enc.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.enc != nil {
+		err := cw.enc.Close()
+		cw.enc = nil
+		return err
+	}
+	return nil
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                       encodingIdentity,
+		"gzip":                   encodingGzip,
+		"gzip, deflate, br":      encodingBrotli,
+		"br;q=0.5, gzip;q=0.8":   encodingGzip,
+		"br;q=0, gzip":           encodingGzip,
+		"identity":               encodingIdentity,
+		"*":                      encodingBrotli,
+		"deflate, gzip;q=0.1":    encodingGzip,
+		"gzip;q=0, br;q=0":       encodingIdentity,
+		"GZIP;q=1.0, x-gzip;q=0": encodingGzip,
+	}
+	for header, expected := range cases {
+		if got := negotiateEncoding(header); got != expected {
+			t.Errorf("negotiateEncoding(%q): expected %q, got %q", header, expected, got)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"name":"Mascaras","brand":"Vivienne Sabo"}`, 100)
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/small" {
+			io.WriteString(w, `{}`)
+			return
+		}
+		io.WriteString(w, large)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	if rr.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary header, got %q", rr.Header().Get("Vary"))
+	}
+	gr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(gr)
+	if string(decoded) != large {
+		t.Errorf("Decoded gzip body does not match original")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("Expected br encoding, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	decoded, _ = io.ReadAll(brotli.NewReader(rr.Body))
+	if string(decoded) != large {
+		t.Errorf("Decoded brotli body does not match original")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected small body to be sent uncompressed, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	if rr.Body.String() != `{}` {
+		t.Errorf("Expected body %q, got %q", `{}`, rr.Body.String())
+	}
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/exporter"
+)
+
+const (
+	exportPageSize   = 500
+	exportFlushEvery = 500
+)
+
+func parseOrderFilter(query url.Values) (db.OrderFilter, error) {
+	filter := db.OrderFilter{
+		CustomerID:      query.Get("customer_id"),
+		DeliveryService: query.Get("delivery_service"),
+	}
+	if v := query.Get("created_from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid created_from: %w", err)
+		}
+		filter.CreatedFrom = t
+	}
+	if v := query.Get("created_to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid created_to: %w", err)
+		}
+		filter.CreatedTo = t
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return filter, fmt.Errorf("invalid limit: %q", v)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func restrictRecord(view auth.View, record db.OrderRecord) db.OrderRecord {
+	switch view {
+	case auth.ViewFull:
+	case auth.ViewFinance:
+		record.Delivery = auth.MaskDelivery(record.Delivery)
+	default:
+		record.Delivery = auth.MaskDelivery(record.Delivery)
+		record.Payment = db.Payment{}
+	}
+	return record
+}
+
+func exportHandler(database *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := responseView(r)
+		if view == auth.ViewNone {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		query := r.URL.Query()
+		format, err := exporter.ParseFormat(valueOr(query.Get("format"), string(exporter.FormatNDJSON)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defaultShape := exporter.ShapeFlat
+		if format == exporter.FormatNDJSON {
+			defaultShape = exporter.ShapeNested
+		}
+		shape, err := exporter.ParseShape(valueOr(query.Get("shape"), string(defaultShape)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter, err := parseOrderFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Println("Failed to clear write deadline for order export:", err)
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", format.ContentType())
+		h.Set("Content-Disposition", `attachment; filename="orders`+format.Extension()+`"`)
+		h.Set("Cache-Control", "no-store")
+
+		ew, err := exporter.NewWriter(w, format, shape)
+		if err != nil {
+			http.Error(w, "Failed to start export", http.StatusInternalServerError)
+			log.Println("Failed to create export writer:", err)
+			return
+		}
+
+		count, err := exporter.Export(ew, func(fn func(db.OrderRecord) error) error {
+			n := 0
+			return db.StreamOrders(r.Context(), database, filter, exportPageSize, func(record db.OrderRecord) error {
+				if err := fn(restrictRecord(view, record)); err != nil {
+					return err
+				}
+				n++
+				if n%exportFlushEvery == 0 {
+					if err := ew.Flush(); err != nil {
+						return err
+					}
+					rc.Flush()
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			log.Printf("Order export aborted after %d orders: %v request_id=%s\n", count, err, RequestIDFromContext(r.Context()))
+			panic(http.ErrAbortHandler)
+		}
+		log.Printf("Exported %d orders as %s/%s request_id=%s\n", count, format, shape, RequestIDFromContext(r.Context()))
+	}
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+)
+
+func TestExportRejectsInvalidParameters(t *testing.T) {
+	handler := exportHandler(nil)
+	for _, query := range []string{"format=xlsx", "shape=tree", "created_from=yesterday", "limit=-1"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/orders/export?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Query %q: expected status code %v, got %v", query, http.StatusBadRequest, rr.Code)
+		}
+	}
+}
+
+func TestRestrictRecord(t *testing.T) {
+	record := db.OrderRecord{
+		Delivery: db.Delivery{Name: "Test Testov", Phone: "+9720000000"},
+		Payment:  db.Payment{Transaction: "t", Amount: 100},
+	}
+
+	if full := restrictRecord(auth.ViewFull, record); full.Delivery.Name != "Test Testov" || full.Payment.Amount != 100 {
+		t.Errorf("Expected full view to keep the record, got %+v", full)
+	}
+	if finance := restrictRecord(auth.ViewFinance, record); finance.Delivery.Name == "Test Testov" || finance.Payment.Amount != 100 {
+		t.Errorf("Expected finance view to mask delivery only, got %+v", finance)
+	}
+	if support := restrictRecord(auth.ViewSupport, record); support.Delivery.Name == "Test Testov" || support.Payment.Amount != 0 {
+		t.Errorf("Expected support view to mask delivery and drop payment, got %+v", support)
+	}
+}
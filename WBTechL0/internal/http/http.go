@@ -1,79 +1,319 @@
 package http
 
 import (
+	"WBTechL0/internal/auth"
 	"WBTechL0/internal/cache"
 	"WBTechL0/internal/db"
+	"WBTechL0/internal/fx"
+	"WBTechL0/internal/money"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"path"
+	"strconv"
+	"time"
 )
 
-func StartServer(orderCache *cache.Cache) {
-	http.Handle("/", http.FileServer(http.Dir("./assets")))
-
-	http.HandleFunc("/order/", func(w http.ResponseWriter, r *http.Request) {
-		orderID := r.URL.Path[len("/order/"):]
+func orderHandler(orderCache *cache.Cache, rates *fx.Rates) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orderID := r.PathValue("id")
 		log.Println("Received request for order:", orderID)
 		if orderID == "" {
 			http.Error(w, "Order ID is required", http.StatusBadRequest)
 			return
 		}
 
-		order, err := orderCache.GetOrder(orderID)
+		view := responseView(r)
+		if view == auth.ViewNone {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		opts, err := parseRenderOptions(r, rates)
+		if err != nil {
+			renderOptionsFailed(w, err)
+			return
+		}
+
+		variant := opts.variant(view)
+		if body, found := orderCache.GetResponse(orderID, variant); found {
+			log.Println("Serving pre-serialized order response:", orderID)
+			writeCachedJSON(w, r, orderCache, orderID, variant, body)
+			return
+		}
+
+		ctx := r.Context()
+		order, err := orderCache.GetOrder(ctx, orderID)
 		if err != nil {
-			http.Error(w, "Order not found", http.StatusNotFound)
-			log.Println("Order not found in cache or DB:", orderID)
+			lookupFailed(w, err, "Order not found")
+			log.Printf("Failed to load order %s: %v\n", orderID, err)
 			return
 		}
 
-		delivery, err := orderCache.GetDelivery(orderID)
+		delivery, err := orderCache.GetDelivery(ctx, orderID)
 		if err != nil {
-			http.Error(w, "Delivery information not found", http.StatusNotFound)
-			log.Println("Delivery information not found for order:", orderID)
+			lookupFailed(w, err, "Delivery information not found")
+			log.Printf("Failed to load order %s: %v\n", orderID, err)
 			return
 		}
 
-		payment, err := orderCache.GetPayment(orderID)
+		payment, err := orderCache.GetPayment(ctx, orderID)
 		if err != nil {
-			http.Error(w, "Payment information not found", http.StatusNotFound)
-			log.Println("Payment information not found for order:", orderID)
+			lookupFailed(w, err, "Payment information not found")
+			log.Printf("Failed to load order %s: %v\n", orderID, err)
 			return
 		}
 
-		items, err := orderCache.GetItems(orderID)
+		items, err := orderCache.GetItems(ctx, orderID)
 		if err != nil {
-			http.Error(w, "Items information not found", http.StatusNotFound)
-			log.Println("Items information not found for order:", orderID)
+			lookupFailed(w, err, "Items information not found")
+			log.Printf("Failed to load order %s: %v\n", orderID, err)
 			return
 		}
 
-		fullOrder := struct {
-			Order    *db.Order    `json:"order"`
-			Delivery *db.Delivery `json:"delivery"`
-			Payment  *db.Payment  `json:"payment"`
-			Items    []db.Item    `json:"items"`
-		}{
+		fullOrder, err := restrictOrder(view, orderResponse{
 			Order:    order,
 			Delivery: delivery,
 			Payment:  payment,
 			Items:    items,
+		}).render(opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
 		}
 
 		log.Printf("Responding with order details: %+v\n", fullOrder)
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(fullOrder); err != nil {
+		body, err := json.Marshal(fullOrder)
+		if err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 			log.Println("Failed to encode response for order:", orderID)
+			return
 		}
-	})
+		body = append(body, '\n')
+		orderCache.SetResponse(orderID, variant, body)
+
+		writeCachedJSON(w, r, orderCache, orderID, variant, body)
+	}
+}
+
+func lookupFailed(w http.ResponseWriter, err error, notFound string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "Timed out loading order", http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled):
+		http.Error(w, "Request canceled", http.StatusServiceUnavailable)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, notFound, http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to load order", http.StatusInternalServerError)
+	}
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+type orderResponse struct {
+	Order            *db.Order         `json:"order"`
+	Delivery         *db.Delivery      `json:"delivery"`
+	Payment          *db.Payment       `json:"payment,omitempty"`
+	PaymentFormatted *formattedPayment `json:"payment_formatted,omitempty"`
+	PaymentConverted *convertedPayment `json:"payment_converted,omitempty"`
+	ConversionError  string            `json:"payment_conversion_error,omitempty"`
+	Items            []db.Item         `json:"items"`
+}
+
+type formattedPayment struct {
+	Locale       string `json:"locale"`
+	Amount       string `json:"amount"`
+	DeliveryCost string `json:"delivery_cost"`
+	GoodsTotal   string `json:"goods_total"`
+	CustomFee    string `json:"custom_fee"`
+}
+
+type convertedPayment struct {
+	Currency  string `json:"currency"`
+	Amount    string `json:"amount"`
+	Formatted string `json:"formatted"`
+	Rate      string `json:"rate"`
+	RateDate  string `json:"rate_date"`
+}
+
+var errConversionUnavailable = errors.New("Currency conversion is not configured")
+
+type renderOptions struct {
+	loc          *time.Location
+	locale       string
+	currency     *money.Currency
+	rates        *fx.Table
+	ratesVersion uint64
+}
+
+func parseRenderOptions(r *http.Request, rates *fx.Rates) (renderOptions, error) {
+	query := r.URL.Query()
+	opts := renderOptions{loc: time.UTC, rates: rates.Table(), ratesVersion: rates.Version()}
+	if locale := query.Get("locale"); locale != "" {
+		opts.locale = money.SupportedLocale(locale)
+	}
+	if name := query.Get("tz"); name != "" {
+		loc, err := time.LoadLocation(path.Clean(name))
+		if err != nil {
+			return renderOptions{}, fmt.Errorf("Unknown time zone %q", name)
+		}
+		opts.loc = loc
+	}
+	if code := query.Get("currency"); code != "" {
+		if opts.rates == nil {
+			return renderOptions{}, errConversionUnavailable
+		}
+		currency, err := money.LookupCurrency(code)
+		if err != nil {
+			return renderOptions{}, fmt.Errorf("Unknown currency %q", code)
+		}
+		opts.currency = &currency
+	}
+	return opts, nil
+}
+
+func renderOptionsFailed(w http.ResponseWriter, err error) {
+	if errors.Is(err, errConversionUnavailable) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (o renderOptions) variant(view auth.View) string {
+	variant := string(view)
+	if o.loc != time.UTC {
+		variant += "@" + o.loc.String()
+	}
+	if o.locale != "" {
+		variant += "~" + o.locale
+	}
+	if o.currency != nil {
+		variant += "$" + o.currency.Code + "#" + strconv.FormatUint(o.ratesVersion, 10)
+	}
+	return variant
+}
+
+func (resp orderResponse) render(opts renderOptions) (orderResponse, error) {
+	locale := opts.locale
+	if resp.Order != nil {
+		order := *resp.Order
+		order.DateCreated = order.DateCreated.In(opts.loc)
+		resp.Order = &order
+		if locale == "" {
+			locale = order.Locale
+		}
+	}
+	if resp.Payment != nil {
+		resp.PaymentFormatted = formatPayment(*resp.Payment, locale)
+		if opts.currency != nil {
+			converted, err := convertPayment(*resp.Payment, *opts.currency, opts.rates, locale)
+			if err != nil {
+				err = fmt.Errorf("Cannot convert payment of order %s: %w", resp.Payment.OrderUID, err)
+				resp.ConversionError = err.Error()
+				return resp, err
+			}
+			resp.PaymentConverted = converted
+		}
+	}
+	return resp, nil
+}
+
+func convertPayment(p db.Payment, to money.Currency, rates *fx.Table, locale string) (*convertedPayment, error) {
+	conversion, err := rates.ConvertPayment(p, to)
+	if err != nil {
+		return nil, err
+	}
+	return &convertedPayment{
+		Currency:  to.Code,
+		Amount:    conversion.Money.Decimal(),
+		Formatted: conversion.Money.Format(locale),
+		Rate:      fx.FormatRate(conversion.Rate),
+		RateDate:  conversion.RateDate.Format("2006-01-02"),
+	}, nil
+}
+
+func formatPayment(p db.Payment, locale string) *formattedPayment {
+	currency, err := money.LookupCurrency(p.Currency)
+	if err != nil {
+		return nil
+	}
+	format := func(amount int) string {
+		m, err := money.FromMajor(int64(amount), currency)
+		if err != nil {
+			return strconv.Itoa(amount)
+		}
+		return m.Format(locale)
+	}
+	return &formattedPayment{
+		Locale:       locale,
+		Amount:       format(p.Amount),
+		DeliveryCost: format(p.DeliveryCost),
+		GoodsTotal:   format(p.GoodsTotal),
+		CustomFee:    format(p.CustomFee),
+	}
+}
+
+func responseView(r *http.Request) auth.View {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return auth.ViewFull
+	}
+	return auth.ViewFor(principal)
+}
+
+func restrictOrder(view auth.View, resp orderResponse) orderResponse {
+	resp.Delivery, resp.Payment = auth.Restrict(view, resp.Delivery, resp.Payment)
+	return resp
+}
+
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, orderCache *cache.Cache, orderID, variant string, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding == encodingIdentity || len(body) < minCompressSize {
+		w.Write(body)
+		return
+	}
+
+	encodedVariant := variant + ":" + encoding
+	encoded, found := orderCache.GetResponse(orderID, encodedVariant)
+	if !found {
+		var err error
+		encoded, err = compressBytes(encoding, body)
+		if err != nil {
+			log.Println("Failed to compress response:", err)
+			w.Write(body)
+			return
+		}
+		orderCache.SetResponse(orderID, encodedVariant, encoded)
+	}
+
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Set("Content-Length", strconv.Itoa(len(encoded)))
+	w.Write(encoded)
+}
+
+func loadOrder(ctx context.Context, orderCache *cache.Cache, orderID string) (orderResponse, error) {
+	order, err := orderCache.GetOrder(ctx, orderID)
+	if err != nil {
+		return orderResponse{}, err
+	}
+	delivery, err := orderCache.GetDelivery(ctx, orderID)
+	if err != nil {
+		return orderResponse{}, err
+	}
+	payment, err := orderCache.GetPayment(ctx, orderID)
+	if err != nil {
+		return orderResponse{}, err
 	}
-	if err := http.ListenAndServe("0.0.0.0:"+port, nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	items, err := orderCache.GetItems(ctx, orderID)
+	if err != nil {
+		return orderResponse{}, err
 	}
+	return orderResponse{Order: order, Delivery: delivery, Payment: payment, Items: items}, nil
 }
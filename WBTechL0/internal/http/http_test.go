@@ -1,14 +1,18 @@
 package http
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"WBTechL0/internal/cache"
+	"WBTechL0/internal/config"
 	"WBTechL0/internal/db"
 	_ "github.com/lib/pq"
 )
@@ -93,13 +97,13 @@ func TestOrderHandler(t *testing.T) {
 		},
 	}
 
-	err = db.AddOrder(dbConn, order, delivery, payment, items)
+	err = db.AddOrder(context.Background(), dbConn, order, delivery, payment, items)
 	if err != nil {
 		t.Fatalf("Failed to add order: %v", err)
 	}
 
 	orderCache := cache.NewCache(dbConn)
-	err = orderCache.LoadCacheFromDB()
+	err = orderCache.LoadCacheFromDB(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to load cache from DB: %v", err)
 	}
@@ -110,49 +114,7 @@ func TestOrderHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		orderID := r.URL.Path[len("/order/"):]
-		order, err := orderCache.GetOrder(orderID)
-		if err != nil {
-			http.Error(w, "Order not found", http.StatusNotFound)
-			return
-		}
-
-		delivery, err := orderCache.GetDelivery(orderID)
-		if err != nil {
-			http.Error(w, "Delivery information not found", http.StatusNotFound)
-			return
-		}
-
-		payment, err := orderCache.GetPayment(orderID)
-		if err != nil {
-			http.Error(w, "Payment information not found", http.StatusNotFound)
-			return
-		}
-
-		items, err := orderCache.GetItems(orderID)
-		if err != nil {
-			http.Error(w, "Items information not found", http.StatusNotFound)
-			return
-		}
-
-		fullOrder := struct {
-			Order    *db.Order    `json:"order"`
-			Delivery *db.Delivery `json:"delivery"`
-			Payment  *db.Payment  `json:"payment"`
-			Items    []db.Item    `json:"items"`
-		}{
-			Order:    order,
-			Delivery: delivery,
-			Payment:  payment,
-			Items:    items,
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(fullOrder); err != nil {
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		}
-	})
+	handler := NewServer(orderCache, config.Load().HTTP).Handler()
 	handler.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusOK {
@@ -164,3 +126,20 @@ func TestOrderHandler(t *testing.T) {
 		t.Fatalf("Failed to clear test data: %v", err)
 	}
 }
+
+func TestLookupFailedStatus(t *testing.T) {
+	cases := map[error]int{
+		sql.ErrNoRows:            http.StatusNotFound,
+		context.DeadlineExceeded: http.StatusGatewayTimeout,
+		context.Canceled:         http.StatusServiceUnavailable,
+		driver.ErrBadConn:        http.StatusInternalServerError,
+		errors.New("pq: syntax"): http.StatusInternalServerError,
+	}
+	for err, expected := range cases {
+		rr := httptest.NewRecorder()
+		lookupFailed(rr, fmt.Errorf("querying order: %w", err), "Order not found")
+		if rr.Code != expected {
+			t.Errorf("Error %v: expected status code %v, got %v", err, expected, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyTTL        = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+	idempotencySweepEvery = time.Minute
+)
+
+type idempotencyState int
+
+const (
+	idempotencyNew idempotencyState = iota
+	idempotencyInFlight
+	idempotencyMismatch
+	idempotencyReplay
+)
+
+type idempotentResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+type idempotencyEntry struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	response    idempotentResponse
+	expires     time.Time
+}
+
+type idempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	ttl       time.Duration
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+func (s *idempotencyStore) begin(key string, body []byte) (idempotencyState, idempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweepLocked(now)
+
+	fingerprint := sha256.Sum256(body)
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		switch {
+		case entry.fingerprint != fingerprint:
+			return idempotencyMismatch, idempotentResponse{}
+		case !entry.done:
+			return idempotencyInFlight, idempotentResponse{}
+		default:
+			return idempotencyReplay, entry.response
+		}
+	}
+
+	s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, expires: now.Add(s.ttl)}
+	return idempotencyNew, idempotentResponse{}
+}
+
+func (s *idempotencyStore) complete(key string, resp idempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok {
+		entry.done = true
+		entry.response = resp
+	}
+}
+
+func (s *idempotencyStore) abandon(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+func (s *idempotencyStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < idempotencySweepEvery {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
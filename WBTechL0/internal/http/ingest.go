@@ -0,0 +1,175 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+	"WBTechL0/internal/ingest"
+	"WBTechL0/internal/message"
+)
+
+const maxOrderBodyBytes = 1 << 20
+
+type ingestError struct {
+	Error string `json:"error"`
+}
+
+func ingestHandler(database *sql.DB, publisher events.Publisher, idempotency *idempotencyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok && !principal.HasRole(auth.RoleAdmin) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodyBytes))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		key := r.Header.Get("Idempotency-Key")
+		if len(key) > maxIdempotencyKeyLen {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+		if key != "" {
+			scopedKey := clientKey(r) + "|" + key
+			state, stored := idempotency.begin(scopedKey, body)
+			switch state {
+			case idempotencyMismatch:
+				writeIngestJSON(w, http.StatusUnprocessableEntity, ingestError{Error: "Idempotency-Key was already used with a different payload"})
+				return
+			case idempotencyInFlight:
+				writeIngestJSON(w, http.StatusConflict, ingestError{Error: "A request with this Idempotency-Key is still being processed"})
+				return
+			case idempotencyReplay:
+				for name, values := range stored.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.status)
+				w.Write(stored.body)
+				return
+			}
+
+			rec := &idempotentResponse{header: make(http.Header)}
+			ingestOrder(recordingWriter{ResponseWriter: w, rec: rec}, r, database, publisher, body)
+			if rec.status >= http.StatusInternalServerError {
+				idempotency.abandon(scopedKey)
+			} else {
+				idempotency.complete(scopedKey, *rec)
+			}
+			return
+		}
+
+		ingestOrder(w, r, database, publisher, body)
+	}
+}
+
+func ingestOrder(w http.ResponseWriter, r *http.Request, database *sql.DB, publisher events.Publisher, body []byte) {
+	format, err := message.ParseFormat(r.Header.Get("Content-Type"))
+	if err != nil {
+		writeIngestJSON(w, http.StatusUnsupportedMediaType, ingestError{Error: err.Error()})
+		return
+	}
+
+	order, err := message.DecodeAs(format, body)
+	if errors.Is(err, message.ErrUnsupportedVersion) || errors.Is(err, message.ErrUnsupportedFormat) {
+		writeIngestJSON(w, http.StatusUnprocessableEntity, ingestError{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeIngestJSON(w, http.StatusBadRequest, ingestError{Error: "Invalid " + format.String() + " payload: " + err.Error()})
+		return
+	}
+
+	if err := order.CheckRequired(); err != nil {
+		writeIngestJSON(w, http.StatusUnprocessableEntity, ingestError{Error: err.Error()})
+		return
+	}
+	if err := order.CheckPayment(); err != nil {
+		writeIngestJSON(w, http.StatusUnprocessableEntity, ingestError{Error: err.Error()})
+		return
+	}
+
+	evt, err := ingest.StoreOrder(r.Context(), database, publisher, order)
+	switch {
+	case db.IsUniqueViolation(err):
+		writeIngestJSON(w, http.StatusConflict, ingestError{Error: "Order " + order.OrderUID + " already exists"})
+		return
+	case errors.Is(err, message.ErrInvalidOrder):
+		writeIngestJSON(w, http.StatusUnprocessableEntity, ingestError{Error: err.Error()})
+		return
+	case err != nil:
+		log.Printf("Failed to ingest order %s: %v request_id=%s\n", order.OrderUID, err, RequestIDFromContext(r.Context()))
+		writeIngestJSON(w, http.StatusInternalServerError, ingestError{Error: "Failed to store order"})
+		return
+	}
+
+	log.Printf("Order %s ingested over HTTP request_id=%s\n", evt.Order.OrderUID, RequestIDFromContext(r.Context()))
+	w.Header().Set("Location", "/order/"+evt.Order.OrderUID)
+	writeIngestJSON(w, http.StatusCreated, orderResponse{
+		Order:    &evt.Order,
+		Delivery: &evt.Delivery,
+		Payment:  &evt.Payment,
+		Items:    evt.Items,
+	})
+}
+
+func writeIngestJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Failed to encode ingest response:", err)
+	}
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	rec *idempotentResponse
+}
+
+func (rw recordingWriter) WriteHeader(status int) {
+	rw.rec.status = status
+	for name, values := range rw.ResponseWriter.Header() {
+		if name == "Location" || name == "Content-Type" {
+			rw.rec.header[name] = values
+		}
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw recordingWriter) Write(p []byte) (int, error) {
+	rw.rec.body = append(rw.rec.body, p...)
+	return rw.ResponseWriter.Write(p)
+}
+
+func schemaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version, err := strconv.Atoi(r.PathValue("version"))
+		if err != nil {
+			writeIngestJSON(w, http.StatusBadRequest, ingestError{Error: "Invalid schema version"})
+			return
+		}
+		schema, err := message.Schema(version)
+		if err != nil {
+			writeIngestJSON(w, http.StatusNotFound, ingestError{Error: err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(schema)
+	}
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/message"
+)
+
+func TestIngestRejectsInvalidOrders(t *testing.T) {
+	handler := ingestHandler(nil, nil, newIdempotencyStore(time.Hour))
+
+	cases := map[string]int{
+		`{"order_uid":`: http.StatusBadRequest,
+		`{"order_uid":"","date_created":"2021-11-26T06:22:19Z"}`:                                 http.StatusUnprocessableEntity,
+		`{"order_uid":"b563feb7b2b84b6test","date_created":"yesterday"}`:                         http.StatusUnprocessableEntity,
+		`{"order_uid":"b563feb7b2b84b6test","date_created":"2021-11-26T06:22:19Z","payment":{}}`: http.StatusUnprocessableEntity,
+		`{"order_uid":"b563feb7b2b84b6test","date_created":"2021-11-26T06:22:19Z","items":[{}]}`: http.StatusUnprocessableEntity,
+		`{"order_uid":"b563feb7b2b84b6test","colour":"red"}`:                                     http.StatusBadRequest,
+		`{"schema_version":9,"payload":{}}`:                                                      http.StatusUnprocessableEntity,
+	}
+	for body, expected := range cases {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(body)))
+		if rr.Code != expected {
+			t.Errorf("Body %q: expected status code %v, got %v", body, expected, rr.Code)
+		}
+	}
+}
+
+func TestIngestBodyErrors(t *testing.T) {
+	handler := ingestHandler(nil, nil, newIdempotencyStore(time.Hour))
+
+	cases := map[string]struct {
+		body     io.Reader
+		expected int
+	}{
+		"too large":  {strings.NewReader(strings.Repeat(" ", maxOrderBodyBytes+1)), http.StatusRequestEntityTooLarge},
+		"read error": {io.MultiReader(strings.NewReader("{"), &errReader{errors.New("connection reset")}), http.StatusBadRequest},
+	}
+	for name, c := range cases {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/orders", c.body))
+		if rr.Code != c.expected {
+			t.Errorf("%s: expected status code %v, got %v", name, c.expected, rr.Code)
+		}
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestItemStatusValidation(t *testing.T) {
+	handler := itemStatusHandler(nil, nil)
+	cases := []struct {
+		chrtID   string
+		body     string
+		expected int
+	}{
+		{"abc", `{"status":202}`, http.StatusBadRequest},
+		{"0", `{"status":202}`, http.StatusBadRequest},
+		{"9934930", `{"status":`, http.StatusBadRequest},
+		{"9934930", `{"status":202,"name":"x"}`, http.StatusBadRequest},
+		{"9934930", `{}`, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/orders/a/items/"+tc.chrtID, strings.NewReader(tc.body))
+		req.SetPathValue("id", "a")
+		req.SetPathValue("chrt_id", tc.chrtID)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.expected {
+			t.Errorf("chrt_id %s with %s: expected status code %v, got %v", tc.chrtID, tc.body, tc.expected, rr.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/orders/a/items/1", strings.NewReader(`{"status":202}`))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "agent", Roles: []auth.Role{auth.RoleSupport}}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %v for a non-admin, got %v", http.StatusForbidden, rr.Code)
+	}
+}
+
+func TestIngestDetectsContentType(t *testing.T) {
+	handler := ingestHandler(nil, nil, newIdempotencyStore(time.Hour))
+	invalid, err := message.EncodeAs(message.FormatProtobuf, message.Order{OrderUID: "b563feb7b2b84b6test"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cases := []struct {
+		contentType string
+		body        string
+		expected    int
+	}{
+		{message.ContentTypeProtobuf, string(invalid), http.StatusUnprocessableEntity},
+		{message.ContentTypeMsgPack, "not msgpack", http.StatusBadRequest},
+		{"text/plain", `{"order_uid":"b563feb7b2b84b6test"}`, http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.expected {
+			t.Errorf("Content type %s: expected status code %v, got %v", tc.contentType, tc.expected, rr.Code)
+		}
+	}
+}
+
+func TestIngestIdempotencyKey(t *testing.T) {
+	handler := ingestHandler(nil, nil, newIdempotencyStore(time.Hour))
+	body := `{"order_uid":"","date_created":"2021-11-26T06:22:19Z"}`
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "key-1")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := send(body)
+	if first.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status code %v, got %v", http.StatusUnprocessableEntity, first.Code)
+	}
+
+	replay := send(body)
+	if replay.Code != first.Code || replay.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed response %v %q, got %v %q", first.Code, first.Body.String(), replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected Idempotent-Replayed header on replay")
+	}
+
+	mismatch := send(`{"order_uid":"other","date_created":"2021-11-26T06:22:19Z"}`)
+	if mismatch.Code != http.StatusUnprocessableEntity || !strings.Contains(mismatch.Body.String(), "different payload") {
+		t.Errorf("Expected payload mismatch error, got %v %q", mismatch.Code, mismatch.Body.String())
+	}
+}
+
+func TestIdempotencyStoreExpires(t *testing.T) {
+	store := newIdempotencyStore(time.Minute)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	if state, _ := store.begin("k", []byte("a")); state != idempotencyNew {
+		t.Fatalf("Expected new key, got %v", state)
+	}
+	if state, _ := store.begin("k", []byte("a")); state != idempotencyInFlight {
+		t.Errorf("Expected in-flight key, got %v", state)
+	}
+	store.complete("k", idempotentResponse{status: http.StatusCreated})
+	if state, resp := store.begin("k", []byte("a")); state != idempotencyReplay || resp.status != http.StatusCreated {
+		t.Errorf("Expected replay of stored response, got %v %v", state, resp.status)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if state, _ := store.begin("k", []byte("b")); state != idempotencyNew {
+		t.Errorf("Expected expired key to be reusable, got %v", state)
+	}
+}
+
+func TestSchemaHandler(t *testing.T) {
+	cases := map[string]int{
+		"1":   http.StatusOK,
+		"2":   http.StatusOK,
+		"9":   http.StatusNotFound,
+		"two": http.StatusBadRequest,
+	}
+	for version, expected := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/schemas/order/"+version, nil)
+		req.SetPathValue("version", version)
+		rr := httptest.NewRecorder()
+		schemaHandler().ServeHTTP(rr, req)
+		if rr.Code != expected {
+			t.Errorf("Version %s: expected status code %v, got %v", version, expected, rr.Code)
+		}
+	}
+}
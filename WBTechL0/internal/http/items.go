@@ -0,0 +1,83 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+)
+
+const maxItemBodyBytes = 4096
+
+type itemStatusRequest struct {
+	Status *int `json:"status"`
+}
+
+func itemStatusHandler(database *sql.DB, publisher events.Publisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok && !principal.HasRole(auth.RoleAdmin) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		orderID := r.PathValue("id")
+		chrtID, err := strconv.Atoi(r.PathValue("chrt_id"))
+		if err != nil || chrtID == 0 {
+			writeIngestJSON(w, http.StatusBadRequest, ingestError{Error: "chrt_id must be a non-zero integer"})
+			return
+		}
+
+		var req itemStatusRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxItemBodyBytes))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeIngestJSON(w, http.StatusBadRequest, ingestError{Error: "Invalid JSON payload: " + err.Error()})
+			return
+		}
+		if req.Status == nil {
+			writeIngestJSON(w, http.StatusUnprocessableEntity, ingestError{Error: "status is required"})
+			return
+		}
+
+		err = db.UpdateItemStatus(r.Context(), database, orderID, chrtID, *req.Status)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeIngestJSON(w, http.StatusNotFound, ingestError{Error: "Item " + strconv.Itoa(chrtID) + " of order " + orderID + " not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to update item %d of order %s: %v request_id=%s\n", chrtID, orderID, err, RequestIDFromContext(r.Context()))
+			writeIngestJSON(w, http.StatusInternalServerError, ingestError{Error: "Failed to update item"})
+			return
+		}
+
+		records, err := db.GetOrderRecordsByUIDs(r.Context(), database, []string{orderID})
+		if err != nil || len(records) == 0 {
+			log.Printf("Failed to reload order %s after updating item %d: %v request_id=%s\n", orderID, chrtID, err, RequestIDFromContext(r.Context()))
+			writeIngestJSON(w, http.StatusInternalServerError, ingestError{Error: "Failed to reload order"})
+			return
+		}
+		record := records[0]
+		if publisher != nil {
+			publisher.Publish(events.OrderEvent{
+				Order:    record.Order,
+				Delivery: record.Delivery,
+				Payment:  record.Payment,
+				Items:    record.Items,
+			})
+		}
+
+		log.Printf("Item %d of order %s set to status %d request_id=%s\n", chrtID, orderID, *req.Status, RequestIDFromContext(r.Context()))
+		writeIngestJSON(w, http.StatusOK, orderResponse{
+			Order:    &record.Order,
+			Delivery: &record.Delivery,
+			Payment:  &record.Payment,
+			Items:    record.Items,
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type requestKey struct {
+	route  string
+	method string
+	status int
+}
+
+type durationStat struct {
+	count uint64
+	sum   float64
+}
+
+type gauge struct {
+	help string
+	typ  string
+	fn   func() float64
+}
+
+type Metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[string]*durationStat
+	gauges    map[string]gauge
+	inFlight  atomic.Int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[string]*durationStat),
+		gauges:    make(map[string]gauge),
+	}
+}
+
+func (m *Metrics) RegisterGauge(name, help string, fn func() float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = gauge{help: help, typ: "gauge", fn: fn}
+}
+
+func (m *Metrics) RegisterCounter(name, help string, fn func() float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = gauge{help: help, typ: "counter", fn: fn}
+}
+
+func (m *Metrics) Instrument(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		elapsed := time.Since(start).Seconds()
+
+		m.mu.Lock()
+		m.requests[requestKey{route: route, method: r.Method, status: sw.status}]++
+		stat, ok := m.durations[route]
+		if !ok {
+			stat = &durationStat{}
+			m.durations[route] = stat
+		}
+		stat.count++
+		stat.sum += elapsed
+		m.mu.Unlock()
+	})
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP http_requests_total Total number of HTTP requests.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "http_requests_total{route=%q,method=%q,status=\"%d\"} %d\n", k.route, k.method, k.status, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Time spent serving HTTP requests.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds summary")
+	routes := make([]string, 0, len(m.durations))
+	for route := range m.durations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		stat := m.durations[route]
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{route=%q} %s\n", route, strconv.FormatFloat(stat.sum, 'f', -1, 64))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{route=%q} %d\n", route, stat.count)
+	}
+
+	fmt.Fprintln(w, "# HELP http_requests_in_flight Number of HTTP requests currently being served.")
+	fmt.Fprintln(w, "# TYPE http_requests_in_flight gauge")
+	fmt.Fprintf(w, "http_requests_in_flight %d\n", m.inFlight.Load())
+
+	names := make([]string, 0, len(m.gauges))
+	for name := range m.gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g := m.gauges[name]
+		fmt.Fprintf(w, "# HELP %s %s\n", name, g.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, g.typ)
+		fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(g.fn(), 'f', -1, 64))
+	}
+}
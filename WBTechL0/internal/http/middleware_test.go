@@ -0,0 +1,180 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ratelimit"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}), mw("first"), mw("second"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if strings.Join(calls, ",") != "first,second,handler" {
+		t.Errorf("Unexpected middleware order: %v", calls)
+	}
+}
+
+func TestRequestIDAndRecover(t *testing.T) {
+	var seen string
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		panic("boom")
+	}), RequestID, Recover)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %v, got %v", http.StatusInternalServerError, rr.Code)
+	}
+	if seen != "abc123" || rr.Header().Get("X-Request-ID") != "abc123" {
+		t.Errorf("Expected request ID abc123 to be propagated, got %q", seen)
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	server := NewServer(nil, config.Load().HTTP)
+	server.Metrics().RegisterGauge("test_connected", "Test gauge.", func() float64 { return 1 })
+	server.Metrics().RegisterCounter("test_reconnects_total", "Test counter.", func() float64 { return 3 })
+	handler := server.Handler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/order/", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %v, got %v", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rr.Body.String(), `http_requests_total{route="/order/{id}",method="GET",status="400"} 1`) {
+		t.Errorf("Expected order request to be counted, got:\n%s", rr.Body.String())
+	}
+	for _, line := range []string{"# TYPE test_connected gauge\ntest_connected 1", "# TYPE test_reconnects_total counter\ntest_reconnects_total 3"} {
+		if !strings.Contains(rr.Body.String(), line) {
+			t.Errorf("Expected %q in metrics, got:\n%s", line, rr.Body.String())
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter, err := ratelimit.New(ratelimit.Limit{Rate: 1, Burst: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RateLimit(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("Expected first request to pass with 0 remaining, got %v %q", rr.Code, rr.Header().Get("RateLimit-Remaining"))
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status code %v, got %v", http.StatusTooManyRequests, rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", rr.Header().Get("Retry-After"))
+	}
+
+	req.Header.Set("X-API-Key", "another-client")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected an unauthenticated API key not to get its own bucket, got %v", rr.Code)
+	}
+
+	authed := req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "another-client"}))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, authed)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected request from a different principal to pass, got %v", rr.Code)
+	}
+}
+
+func TestRateLimitSharedAcrossMethods(t *testing.T) {
+	handler := NewServer(nil, config.Load().HTTP, WithRateLimits(config.RateLimitConfig{
+		Enabled: true,
+		Routes:  map[string]config.RateLimit{"/graphql": {Rate: 1, Burst: 1}},
+		Default: config.RateLimit{Rate: 50, Burst: 100},
+	})).Handler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/graphql?query={__typename}", nil))
+	if rr.Code == http.StatusTooManyRequests {
+		t.Fatalf("Expected first request to pass, got %v", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{__typename}"}`)))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected GET and POST /graphql to share a bucket, got %v", rr.Code)
+	}
+}
+
+func TestRateLimitBeforeAuthentication(t *testing.T) {
+	handler := NewServer(nil, config.Load().HTTP,
+		WithAuthenticator(auth.NewAPIKeyAuthenticator([]auth.APIKey{{Key: "secret", Subject: "ops", Roles: []string{"admin"}}})),
+		WithRateLimits(config.RateLimitConfig{Enabled: true, Default: config.RateLimit{Rate: 1, Burst: 1}}),
+	).Handler()
+
+	expected := []int{http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, code := range expected {
+		req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+		req.Header.Set("X-API-Key", "guess")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != code {
+			t.Errorf("Request %d with a bad key: expected status code %v, got %v", i+1, code, rr.Code)
+		}
+	}
+}
+
+func TestQuota(t *testing.T) {
+	quota, err := ratelimit.NewQuota(ratelimit.Quota{Requests: 2, Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Quota(quota)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to pass, got %v", i+1, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status code %v, got %v", http.StatusTooManyRequests, rr.Code)
+	}
+	if rr.Header().Get("X-Quota-Remaining") != "0" || rr.Header().Get("Retry-After") != "3600" {
+		t.Errorf("Unexpected quota headers: %v", rr.Header())
+	}
+}
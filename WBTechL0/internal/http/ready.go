@@ -0,0 +1,38 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+type readinessCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func readinessHandler(checks []readinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := readinessResponse{Status: "ready", Checks: make(map[string]string, len(checks))}
+		status := http.StatusOK
+		for _, c := range checks {
+			if err := c.check(r.Context()); err != nil {
+				resp.Checks[c.name] = err.Error()
+				resp.Status = "not ready"
+				status = http.StatusServiceUnavailable
+				continue
+			}
+			resp.Checks[c.name] = "ok"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"WBTechL0/internal/config"
+)
+
+func TestReadiness(t *testing.T) {
+	natsErr := errors.New("not connected")
+	natsReady := natsErr
+	server := NewServer(nil, config.Load().HTTP,
+		WithReadinessCheck("database", func(context.Context) error { return nil }),
+		WithReadinessCheck("nats", func(context.Context) error { return natsReady }),
+	)
+
+	rr := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status code %v, got %v", http.StatusServiceUnavailable, rr.Code)
+	}
+	var resp readinessResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Checks["database"] != "ok" || resp.Checks["nats"] != natsErr.Error() {
+		t.Errorf("Unexpected checks: %v", resp.Checks)
+	}
+
+	natsReady = nil
+	rr = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status code %v, got %v", http.StatusOK, rr.Code)
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/cache"
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/events"
+	"WBTechL0/internal/fx"
+	"WBTechL0/internal/graphql"
+	"WBTechL0/internal/ratelimit"
+)
+
+type Server struct {
+	cache         *cache.Cache
+	cfg           config.HTTPConfig
+	mux           *http.ServeMux
+	metrics       *Metrics
+	authenticator auth.Authenticator
+	rateLimits    config.RateLimitConfig
+	limiters      map[string]*ratelimit.Limiter
+	quota         *ratelimit.QuotaTracker
+	broker        *events.Broker
+	db            *sql.DB
+	publisher     events.Publisher
+	idempotency   *idempotencyStore
+	readiness     []readinessCheck
+	rates         *fx.Rates
+	hub           *wsHub
+	handler       http.Handler
+	httpServer    *http.Server
+}
+
+type Option func(*Server)
+
+func WithAuthenticator(authenticator auth.Authenticator) Option {
+	return func(s *Server) {
+		s.authenticator = authenticator
+	}
+}
+
+func WithRateLimits(rateLimits config.RateLimitConfig) Option {
+	return func(s *Server) {
+		s.rateLimits = rateLimits
+	}
+}
+
+func WithBroker(broker *events.Broker) Option {
+	return func(s *Server) {
+		s.broker = broker
+	}
+}
+
+func WithIngest(database *sql.DB, publisher events.Publisher) Option {
+	return func(s *Server) {
+		s.db = database
+		s.publisher = publisher
+	}
+}
+
+func WithReadinessCheck(name string, check func(context.Context) error) Option {
+	return func(s *Server) {
+		s.readiness = append(s.readiness, readinessCheck{name: name, check: check})
+	}
+}
+
+func WithExchangeRates(rates *fx.Rates) Option {
+	return func(s *Server) {
+		s.rates = rates
+	}
+}
+
+func NewServer(orderCache *cache.Cache, cfg config.HTTPConfig, opts ...Option) *Server {
+	s := &Server{
+		cache:    orderCache,
+		cfg:      cfg,
+		mux:      http.NewServeMux(),
+		metrics:  NewMetrics(),
+		limiters: make(map[string]*ratelimit.Limiter),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if quota := s.rateLimits.Quota; s.rateLimits.Enabled && quota.Requests > 0 {
+		tracker, err := ratelimit.NewQuota(ratelimit.Quota{Requests: quota.Requests, Window: quota.Window})
+		if err != nil {
+			log.Fatalf("Invalid request quota: %v", err)
+		}
+		s.quota = tracker
+	}
+	s.routes()
+	s.handler = Chain(s.mux,
+		RequestID,
+		Recover,
+		Logging,
+		Compress,
+	)
+	s.httpServer = &http.Server{
+		Addr:         "0.0.0.0:" + s.cfg.Port,
+		Handler:      s.handler,
+		ReadTimeout:  s.cfg.ReadTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
+		IdleTimeout:  s.cfg.IdleTimeout,
+	}
+	return s
+}
+
+func (s *Server) routes() {
+	s.handle("GET /", "/", http.FileServer(http.Dir("./assets")))
+	s.handle("GET /order/{id...}", "/order/{id}", orderHandler(s.cache, s.rates), s.protected("/order/{id}")...)
+	s.handle("POST /api/v1/orders:batchGet", "/api/v1/orders:batchGet", batchGetHandler(s.cache, s.rates, s.cfg.BatchMaxIDs), s.protected("/api/v1/orders:batchGet")...)
+	if s.db != nil {
+		s.idempotency = newIdempotencyStore(idempotencyTTL)
+		s.handle("POST /api/v1/orders", "/api/v1/orders", ingestHandler(s.db, s.publisher, s.idempotency), s.protected("/api/v1/orders")...)
+		s.handle("PATCH /api/v1/orders/{id}/items/{chrt_id}", "/api/v1/orders/{id}/items/{chrt_id}", itemStatusHandler(s.db, s.publisher), s.protected("/api/v1/orders/{id}/items/{chrt_id}")...)
+		s.handleStream("GET /api/v1/orders/export", "/api/v1/orders/export", exportHandler(s.db), s.protected("/api/v1/orders/export")...)
+	}
+	s.handle("GET /api/v1/schemas/order/{version}", "/api/v1/schemas/order/{version}", schemaHandler())
+	s.mux.Handle("GET /metrics", s.metrics)
+	s.mux.Handle("GET /readyz", Chain(readinessHandler(s.readiness), Timeout(s.cfg.RequestTimeout)))
+
+	graphqlHandler, err := graphql.NewHandler(s.cache, s.rates)
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+	s.handle("GET /graphql", "/graphql", graphqlHandler, s.protected("/graphql")...)
+	s.handle("POST /graphql", "/graphql", graphqlHandler, s.protected("/graphql")...)
+
+	if s.broker != nil {
+		s.handleStream("GET /api/v1/orders/stream", "/api/v1/orders/stream", orderStreamHandler(s.broker), s.protected("/api/v1/orders/stream")...)
+		s.metrics.RegisterGauge("order_stream_subscribers", "Number of connected order stream clients.", func() float64 {
+			return float64(s.broker.Subscribers())
+		})
+
+		s.hub = newWSHub(s.broker, s.cache)
+		go s.hub.run()
+		s.handleStream("GET /api/v1/orders/ws", "/api/v1/orders/ws", orderWebSocketHandler(s.hub), append([]Middleware{webSocketCredentials}, s.protected("/api/v1/orders/ws")...)...)
+		s.metrics.RegisterGauge("order_websocket_clients", "Number of connected order tracking WebSocket clients.", func() float64 {
+			return float64(s.hub.clientCount())
+		})
+	}
+}
+
+func (s *Server) protected(route string) []Middleware {
+	if s.authenticator == nil {
+		return nil
+	}
+	middlewares := []Middleware{Authenticate(s.authenticator)}
+	if s.rateLimits.Enabled {
+		middlewares = append(middlewares, RateLimit(s.limiter(route)))
+	}
+	return middlewares
+}
+
+func (s *Server) handle(pattern, route string, handler http.Handler, middlewares ...Middleware) {
+	s.handleStream(pattern, route, handler, append(middlewares, Timeout(s.cfg.RequestTimeout))...)
+}
+
+func (s *Server) handleStream(pattern, route string, handler http.Handler, middlewares ...Middleware) {
+	if s.rateLimits.Enabled {
+		middlewares = append([]Middleware{RateLimit(s.limiter(route))}, middlewares...)
+		if s.quota != nil {
+			middlewares = append(middlewares, Quota(s.quota))
+		}
+	}
+	s.mux.Handle(pattern, s.metrics.Instrument(route, Chain(handler, middlewares...)))
+}
+
+func (s *Server) limiter(route string) *ratelimit.Limiter {
+	if limiter, ok := s.limiters[route]; ok {
+		return limiter
+	}
+	limit := s.rateLimits.For(route)
+	limiter, err := ratelimit.New(ratelimit.Limit{Rate: limit.Rate, Burst: limit.Burst})
+	if err != nil {
+		log.Fatalf("Invalid rate limit for %s: %v", route, err)
+	}
+	s.limiters[route] = limiter
+	return limiter
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
+func (s *Server) ListenAndServe() error {
+	log.Println("Starting HTTP server on", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.hub != nil {
+		s.hub.stop()
+	}
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.httpServer.Close()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/events"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+func orderStreamHandler(broker *events.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := responseView(r)
+		if view == auth.ViewNone {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Println("Failed to clear write deadline for order stream:", err)
+		}
+
+		query := r.URL.Query()
+		filter := events.Filter{
+			DeliveryService: query.Get("delivery_service"),
+			CustomerID:      query.Get("customer_id"),
+		}
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = query.Get("last_event_id")
+		}
+		var lastID uint64
+		if lastEventID != "" {
+			parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			lastID = parsed
+		}
+
+		sub, missed := broker.Subscribe(filter, lastID)
+		defer sub.Close()
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		log.Printf("Order stream client connected, replaying %d events request_id=%s\n", len(missed), RequestIDFromContext(r.Context()))
+
+		fmt.Fprint(w, "retry: 3000\n\n")
+		for _, evt := range missed {
+			if err := writeOrderEvent(w, view, evt); err != nil {
+				return
+			}
+		}
+		rc.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				log.Println("Order stream client disconnected:", RequestIDFromContext(r.Context()))
+				return
+			case evt, ok := <-sub.Events():
+				if !ok {
+					log.Println("Order stream client fell behind, closing:", RequestIDFromContext(r.Context()))
+					return
+				}
+				if err := writeOrderEvent(w, view, evt); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeOrderEvent(w http.ResponseWriter, view auth.View, evt events.OrderEvent) error {
+	resp := restrictOrder(view, orderResponse{
+		Order:    &evt.Order,
+		Delivery: &evt.Delivery,
+		Payment:  &evt.Payment,
+		Items:    evt.Items,
+	})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Println("Failed to encode order event:", err)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", evt.ID, data)
+	return err
+}
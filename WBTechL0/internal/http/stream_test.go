@@ -0,0 +1,65 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+)
+
+func TestOrderStream(t *testing.T) {
+	broker := events.NewBroker(10)
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "first", DeliveryService: "meest"}})
+
+	server := httptest.NewServer(NewServer(nil, config.Load().HTTP, WithBroker(broker)).Handler())
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/orders/stream?delivery_service=meest", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected event stream content type, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	for broker.Subscribers() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "skipped", DeliveryService: "dhl"}})
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "second", DeliveryService: "meest"}})
+
+	reader := bufio.NewReader(resp.Body)
+	var ids []string
+	for len(ids) < 1 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			if strings.Contains(line, `"order_uid":"skipped"`) {
+				t.Fatalf("Filtered order was delivered: %s", line)
+			}
+			if strings.Contains(line, `"order_uid":"second"`) {
+				ids = append(ids, "second")
+			}
+		}
+		if strings.HasPrefix(line, "id: ") {
+			if strings.TrimSpace(line) != "id: 3" {
+				t.Errorf("Unexpected event id line %q", line)
+			}
+		}
+	}
+}
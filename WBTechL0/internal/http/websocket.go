@@ -0,0 +1,401 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/cache"
+	"WBTechL0/internal/events"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait          = 10 * time.Second
+	wsPongWait           = 60 * time.Second
+	wsPingPeriod         = wsPongWait * 9 / 10
+	wsIdleTimeout        = 5 * time.Minute
+	wsMaxMessageSize     = 4096
+	wsMaxSubscriptions   = 100
+	wsClientSendBuffer   = 16
+	wsJanitorInterval    = time.Minute
+	wsResubscribeBackoff = time.Second
+	wsSubprotocol        = "orders.v1"
+	wsAPIKeyPrefix       = "api-key."
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	Subprotocols:    []string{wsSubprotocol},
+}
+
+func webSocketCredentials(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") == "" && r.Header.Get("Authorization") == "" {
+			for _, protocol := range websocket.Subprotocols(r) {
+				encoded, ok := strings.CutPrefix(protocol, wsAPIKeyPrefix)
+				if !ok {
+					continue
+				}
+				if key, err := base64.RawURLEncoding.DecodeString(encoded); err == nil {
+					r = r.Clone(r.Context())
+					r.Header.Set("X-API-Key", string(key))
+				}
+				break
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type wsRequest struct {
+	Action    string   `json:"action"`
+	OrderUIDs []string `json:"order_uids"`
+}
+
+type wsMessage struct {
+	Type     string         `json:"type"`
+	OrderUID string         `json:"order_uid,omitempty"`
+	Order    *orderResponse `json:"data,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+type wsClient struct {
+	hub          *wsHub
+	conn         *websocket.Conn
+	view         auth.View
+	send         chan []byte
+	orders       map[string]struct{}
+	lastActivity time.Time
+	mu           sync.Mutex
+	closed       bool
+}
+
+type wsHub struct {
+	broker  *events.Broker
+	cache   *cache.Cache
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+	byOrder map[string]map[*wsClient]struct{}
+	done    chan struct{}
+}
+
+func newWSHub(broker *events.Broker, orderCache *cache.Cache) *wsHub {
+	return &wsHub{
+		broker:  broker,
+		cache:   orderCache,
+		clients: make(map[*wsClient]struct{}),
+		byOrder: make(map[string]map[*wsClient]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (h *wsHub) run() {
+	janitor := time.NewTicker(wsJanitorInterval)
+	defer janitor.Stop()
+
+	var lastID uint64
+	for {
+		sub, missed := h.broker.Subscribe(events.Filter{}, lastID)
+		for _, evt := range missed {
+			h.dispatch(evt)
+			lastID = evt.ID
+		}
+
+	consume:
+		for {
+			select {
+			case <-h.done:
+				sub.Close()
+				return
+			case evt, ok := <-sub.Events():
+				if !ok {
+					log.Println("WebSocket hub fell behind order events, resubscribing from:", lastID)
+					break consume
+				}
+				h.dispatch(evt)
+				lastID = evt.ID
+			case <-janitor.C:
+				h.closeIdle()
+			}
+		}
+
+		select {
+		case <-h.done:
+			return
+		case <-time.After(wsResubscribeBackoff):
+		}
+	}
+}
+
+func (h *wsHub) stop() {
+	close(h.done)
+	h.mu.Lock()
+	clients := make([]*wsClient, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+	for _, c := range clients {
+		c.close()
+	}
+}
+
+func (h *wsHub) clientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+func (h *wsHub) dispatch(evt events.OrderEvent) {
+	h.mu.Lock()
+	subscribers := make([]*wsClient, 0, len(h.byOrder[evt.Order.OrderUID]))
+	for c := range h.byOrder[evt.Order.OrderUID] {
+		subscribers = append(subscribers, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range subscribers {
+		resp := restrictOrder(c.view, orderResponse{
+			Order:    &evt.Order,
+			Delivery: &evt.Delivery,
+			Payment:  &evt.Payment,
+			Items:    evt.Items,
+		})
+		c.push(wsMessage{Type: "order", OrderUID: evt.Order.OrderUID, Order: &resp})
+	}
+}
+
+func (h *wsHub) register(c *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = struct{}{}
+}
+
+func (h *wsHub) unregister(c *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+	for orderUID := range c.orders {
+		h.removeLocked(c, orderUID)
+	}
+}
+
+func (h *wsHub) subscribe(c *wsClient, orderUIDs []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.lastActivity = time.Now()
+
+	var added []string
+	for _, orderUID := range orderUIDs {
+		if orderUID == "" || len(c.orders) >= wsMaxSubscriptions {
+			continue
+		}
+		if _, ok := c.orders[orderUID]; ok {
+			continue
+		}
+		c.orders[orderUID] = struct{}{}
+		if h.byOrder[orderUID] == nil {
+			h.byOrder[orderUID] = make(map[*wsClient]struct{})
+		}
+		h.byOrder[orderUID][c] = struct{}{}
+		added = append(added, orderUID)
+	}
+	return added
+}
+
+func (h *wsHub) unsubscribe(c *wsClient, orderUIDs []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.lastActivity = time.Now()
+	for _, orderUID := range orderUIDs {
+		delete(c.orders, orderUID)
+		h.removeLocked(c, orderUID)
+	}
+}
+
+func (h *wsHub) removeLocked(c *wsClient, orderUID string) {
+	if clients, ok := h.byOrder[orderUID]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(h.byOrder, orderUID)
+		}
+	}
+}
+
+func (h *wsHub) closeIdle() {
+	h.mu.Lock()
+	var idle []*wsClient
+	for c := range h.clients {
+		if len(c.orders) == 0 && time.Since(c.lastActivity) > wsIdleTimeout {
+			idle = append(idle, c)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, c := range idle {
+		log.Println("Closing idle WebSocket client:", c.conn.RemoteAddr())
+		c.close()
+	}
+}
+
+func (h *wsHub) snapshot(ctx context.Context, c *wsClient, orderUID string) {
+	if h.cache == nil {
+		return
+	}
+	resp, err := loadOrder(ctx, h.cache, orderUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.push(wsMessage{Type: "not_found", OrderUID: orderUID})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load order %s for WebSocket client %s: %v\n", orderUID, c.conn.RemoteAddr(), err)
+		c.push(wsMessage{Type: "error", OrderUID: orderUID, Error: "Failed to load order"})
+		return
+	}
+	resp = restrictOrder(c.view, resp)
+	c.push(wsMessage{Type: "order", OrderUID: orderUID, Order: &resp})
+}
+
+func (c *wsClient) push(msg wsMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("Failed to encode WebSocket message:", err)
+		return
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	slow := false
+	select {
+	case c.send <- data:
+	default:
+		slow = true
+	}
+	c.mu.Unlock()
+
+	if slow {
+		log.Println("WebSocket client is too slow, closing:", c.conn.RemoteAddr())
+		c.close()
+	}
+}
+
+func (c *wsClient) close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.send)
+	c.mu.Unlock()
+
+	c.hub.unregister(c)
+}
+
+func (c *wsClient) readPump(ctx context.Context) {
+	defer func() {
+		c.close()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var req wsRequest
+		if err := c.conn.ReadJSON(&req); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("WebSocket read error:", err)
+			}
+			return
+		}
+
+		switch req.Action {
+		case "subscribe":
+			for _, orderUID := range c.hub.subscribe(c, req.OrderUIDs) {
+				c.push(wsMessage{Type: "subscribed", OrderUID: orderUID})
+				c.hub.snapshot(ctx, c, orderUID)
+			}
+		case "unsubscribe":
+			c.hub.unsubscribe(c, req.OrderUIDs)
+			for _, orderUID := range req.OrderUIDs {
+				c.push(wsMessage{Type: "unsubscribed", OrderUID: orderUID})
+			}
+		default:
+			c.push(wsMessage{Type: "error", Error: "unknown action"})
+		}
+	}
+}
+
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func orderWebSocketHandler(hub *wsHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view := responseView(r)
+		if view == auth.ViewNone {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Println("Failed to upgrade WebSocket connection:", err)
+			return
+		}
+
+		c := &wsClient{
+			hub:          hub,
+			conn:         conn,
+			view:         view,
+			send:         make(chan []byte, wsClientSendBuffer),
+			orders:       make(map[string]struct{}),
+			lastActivity: time.Now(),
+		}
+		hub.register(c)
+		log.Printf("WebSocket client connected from %s request_id=%s\n", conn.RemoteAddr(), RequestIDFromContext(r.Context()))
+
+		go c.writePump()
+		c.readPump(r.Context())
+	}
+}
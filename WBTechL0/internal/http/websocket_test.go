@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/auth"
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+	"github.com/gorilla/websocket"
+)
+
+func TestOrderWebSocket(t *testing.T) {
+	broker := events.NewBroker(10)
+	server := NewServer(nil, config.Load().HTTP, WithBroker(broker))
+	defer server.Shutdown(context.Background())
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/orders/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(wsRequest{Action: "subscribe", OrderUIDs: []string{"tracked"}}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	var msg wsMessage
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "subscribed" || msg.OrderUID != "tracked" {
+		t.Fatalf("Expected subscription confirmation, got %+v (%v)", msg, err)
+	}
+
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "other"}})
+	broker.Publish(events.OrderEvent{Order: db.Order{OrderUID: "tracked", TrackNumber: "WBILMTESTTRACK"}, Items: []db.Item{{Status: 202}}})
+
+	msg = wsMessage{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read order update: %v", err)
+	}
+	if msg.Type != "order" || msg.OrderUID != "tracked" || msg.Order == nil || msg.Order.Order.TrackNumber != "WBILMTESTTRACK" {
+		t.Errorf("Unexpected order update: %+v", msg)
+	}
+	if server.hub.clientCount() != 1 {
+		t.Errorf("Expected one registered client, got %d", server.hub.clientCount())
+	}
+}
+
+func TestOrderWebSocketSubprotocolAuth(t *testing.T) {
+	server := NewServer(nil, config.Load().HTTP,
+		WithBroker(events.NewBroker(10)),
+		WithAuthenticator(auth.NewAPIKeyAuthenticator([]auth.APIKey{{Key: "secret", Subject: "agent", Roles: []string{"support"}}})),
+	)
+	defer server.Shutdown(context.Background())
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/orders/ws"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected a 401 without credentials, got %v (%v)", resp, err)
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsSubprotocol, wsAPIKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte("secret"))}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Expected the API key subprotocol to authenticate, got %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != wsSubprotocol {
+		t.Errorf("Expected subprotocol %s, got %q", wsSubprotocol, conn.Subprotocol())
+	}
+}
@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/message"
+)
+
+const DefaultBatchSize = 500
+
+type StoreFunc func(records []db.OrderRecord) ([]error, error)
+
+type Options struct {
+	BatchSize  int
+	Rejects    io.Writer
+	Checkpoint string
+}
+
+type Report struct {
+	Read       int
+	Imported   int
+	Rejected   int
+	Skipped    int
+	LastRecord int
+}
+
+type reject struct {
+	Record int    `json:"record"`
+	Error  string `json:"error"`
+	Raw    string `json:"raw"`
+}
+
+type pending struct {
+	num  int
+	raw  []byte
+	data db.OrderRecord
+}
+
+func Run(src io.Reader, store StoreFunc, opts Options) (Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	var report Report
+	resumeAfter, err := LoadCheckpoint(opts.Checkpoint)
+	if err != nil {
+		return report, err
+	}
+	if resumeAfter > 0 {
+		log.Println("Resuming import after record:", resumeAfter)
+	}
+
+	reader, err := NewReader(src)
+	if err != nil {
+		return report, err
+	}
+
+	var rejects *json.Encoder
+	if opts.Rejects != nil {
+		rejects = json.NewEncoder(opts.Rejects)
+	}
+	rejectRecord := func(num int, raw []byte, err error) error {
+		report.Rejected++
+		if rejects == nil {
+			log.Printf("Rejected record %d: %v\n", num, err)
+			return nil
+		}
+		return rejects.Encode(reject{Record: num, Error: err.Error(), Raw: string(raw)})
+	}
+
+	batch := make([]pending, 0, opts.BatchSize)
+	lastSeen := resumeAfter
+	flush := func() error {
+		if len(batch) > 0 {
+			records := make([]db.OrderRecord, len(batch))
+			for i, p := range batch {
+				records[i] = p.data
+			}
+			errs, err := store(records)
+			if err != nil {
+				return err
+			}
+			for i, p := range batch {
+				if errs != nil && errs[i] != nil {
+					if err := rejectRecord(p.num, p.raw, errs[i]); err != nil {
+						return err
+					}
+					continue
+				}
+				report.Imported++
+			}
+			batch = batch[:0]
+		}
+		report.LastRecord = lastSeen
+		return SaveCheckpoint(opts.Checkpoint, lastSeen)
+	}
+
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if flushErr := flush(); flushErr != nil {
+				return report, flushErr
+			}
+			return report, err
+		}
+		if rec.Num <= resumeAfter {
+			report.Skipped++
+			continue
+		}
+		report.Read++
+		lastSeen = rec.Num
+
+		order, err := message.Decode(rec.Data)
+		if err == nil {
+			var record db.OrderRecord
+			record, err = order.Record()
+			if err == nil {
+				batch = append(batch, pending{num: rec.Num, raw: rec.Data, data: record})
+			}
+		}
+		if err != nil {
+			if err := rejectRecord(rec.Num, rec.Data, err); err != nil {
+				return report, err
+			}
+		}
+
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+			log.Printf("Imported %d orders, rejected %d, at record %d\n", report.Imported, report.Rejected, report.LastRecord)
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func LoadCheckpoint(path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func SaveCheckpoint(path string, num int) error {
+	if path == "" {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(strconv.Itoa(num) + "\n"); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"WBTechL0/internal/db"
+)
+
+func order(uid string) string {
+	return fmt.Sprintf(`{"order_uid":%q,"date_created":"2021-11-26T06:22:19Z","payment":{"transaction":%q,"currency":"USD"},"items":[{"chrt_id":1}]}`, uid, uid)
+}
+
+func readAll(t *testing.T, input string) []Record {
+	t.Helper()
+	reader, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	var records []Record
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Failed to read record: %v", err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestReaderFormats(t *testing.T) {
+	ndjson := readAll(t, order("a")+"\n\n"+order("b")+"\n")
+	if len(ndjson) != 2 || ndjson[1].Num != 3 {
+		t.Errorf("Expected 2 NDJSON records with line numbers, got %+v", ndjson)
+	}
+
+	array := readAll(t, "  [\n"+order("a")+",\n"+order("b")+"]\n")
+	if len(array) != 2 || array[0].Num != 1 || array[1].Num != 2 {
+		t.Errorf("Expected 2 array records, got %+v", array)
+	}
+	var decoded struct {
+		OrderUID string `json:"order_uid"`
+	}
+	if err := json.Unmarshal(array[1].Data, &decoded); err != nil || decoded.OrderUID != "b" {
+		t.Errorf("Expected second array element to be order b, got %s", array[1].Data)
+	}
+
+	if records := readAll(t, ""); len(records) != 0 {
+		t.Errorf("Expected no records from empty input, got %d", len(records))
+	}
+}
+
+func TestRunBatchesRejectsAndCheckpoints(t *testing.T) {
+	input := strings.Join([]string{
+		order("a"),
+		`{"order_uid":`,
+		order("b"),
+		`{"order_uid":"c","date_created":"yesterday"}`,
+		order("dup"),
+	}, "\n")
+
+	var batches [][]string
+	store := func(records []db.OrderRecord) ([]error, error) {
+		var uids []string
+		errs := make([]error, len(records))
+		for i, r := range records {
+			uids = append(uids, r.Order.OrderUID)
+			if r.Order.OrderUID == "dup" {
+				errs[i] = errors.New("duplicate key")
+			}
+		}
+		batches = append(batches, uids)
+		return errs, nil
+	}
+
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	var rejects bytes.Buffer
+	report, err := Run(strings.NewReader(input), store, Options{BatchSize: 2, Rejects: &rejects, Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if report.Read != 5 || report.Imported != 2 || report.Rejected != 3 || report.LastRecord != 5 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || batches[1][0] != "dup" {
+		t.Errorf("Unexpected batches: %v", batches)
+	}
+
+	var lines []reject
+	dec := json.NewDecoder(&rejects)
+	for dec.More() {
+		var r reject
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("Failed to decode reject: %v", err)
+		}
+		lines = append(lines, r)
+	}
+	if len(lines) != 3 || lines[0].Record != 2 || lines[1].Record != 4 || lines[2].Record != 5 {
+		t.Errorf("Unexpected rejects: %+v", lines)
+	}
+
+	saved, err := LoadCheckpoint(checkpoint)
+	if err != nil || saved != 5 {
+		t.Errorf("Expected checkpoint 5, got %d (%v)", saved, err)
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	if err := os.WriteFile(checkpoint, []byte("2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var imported []string
+	store := func(records []db.OrderRecord) ([]error, error) {
+		for _, r := range records {
+			imported = append(imported, r.Order.OrderUID)
+		}
+		return nil, nil
+	}
+
+	input := "[" + strings.Join([]string{order("a"), order("b"), order("c")}, ",") + "]"
+	report, err := Run(strings.NewReader(input), store, Options{Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if report.Skipped != 2 || len(imported) != 1 || imported[0] != "c" {
+		t.Errorf("Expected only order c to be imported, got %v (report %+v)", imported, report)
+	}
+}
+
+func TestRunStopsOnStoreFailure(t *testing.T) {
+	checkpoint := filepath.Join(t.TempDir(), "import.checkpoint")
+	store := func(records []db.OrderRecord) ([]error, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	_, err := Run(strings.NewReader(order("a")+"\n"+order("b")), store, Options{BatchSize: 1, Checkpoint: checkpoint})
+	if err == nil {
+		t.Fatal("Expected store failure to abort the import")
+	}
+	if saved, _ := LoadCheckpoint(checkpoint); saved != 0 {
+		t.Errorf("Expected no checkpoint after failed batch, got %d", saved)
+	}
+}
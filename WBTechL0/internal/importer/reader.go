@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type Record struct {
+	Num  int
+	Data []byte
+}
+
+type Reader struct {
+	br    *bufio.Reader
+	dec   *json.Decoder
+	array bool
+	num   int
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return &Reader{br: br}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+			continue
+		case '[':
+			dec := json.NewDecoder(br)
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return &Reader{dec: dec, array: true}, nil
+		}
+		return &Reader{br: br}, nil
+	}
+}
+
+func (r *Reader) Next() (Record, error) {
+	if r.array {
+		return r.nextElement()
+	}
+	return r.nextLine()
+}
+
+func (r *Reader) nextLine() (Record, error) {
+	for {
+		line, err := r.br.ReadBytes('\n')
+		if len(line) > 0 {
+			r.num++
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				return Record{Num: r.num, Data: line}, nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return Record{}, io.EOF
+		}
+		if err != nil {
+			return Record{}, err
+		}
+	}
+}
+
+func (r *Reader) nextElement() (Record, error) {
+	if !r.dec.More() {
+		if _, err := r.dec.Token(); err != nil {
+			return Record{}, fmt.Errorf("reading end of JSON array: %w", err)
+		}
+		return Record{}, io.EOF
+	}
+	var raw json.RawMessage
+	if err := r.dec.Decode(&raw); err != nil {
+		return Record{}, fmt.Errorf("decoding array element %d: %w", r.num+1, err)
+	}
+	r.num++
+	return Record{Num: r.num, Data: raw}, nil
+}
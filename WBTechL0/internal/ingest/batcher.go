@@ -0,0 +1,234 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+)
+
+type StoreFunc func(ctx context.Context, records []db.OrderRecord) ([]error, error)
+
+type Message struct {
+	Record db.OrderRecord
+	Ack    func() error
+	Nak    func() error
+	Term   func() error
+}
+
+func (m Message) Reject() error {
+	if m.Term != nil {
+		return m.Term()
+	}
+	if m.Ack != nil {
+		return m.Ack()
+	}
+	return nil
+}
+
+func (m Message) Retry() error {
+	if m.Nak != nil {
+		return m.Nak()
+	}
+	return nil
+}
+
+type Batcher struct {
+	store      StoreFunc
+	publisher  events.Publisher
+	size       int
+	interval   time.Duration
+	retryDelay time.Duration
+	abandoned  bool
+	mu         sync.RWMutex
+	closed     bool
+	messages   chan Message
+	stopping   chan struct{}
+	done       chan struct{}
+}
+
+func NewBatcher(store StoreFunc, publisher events.Publisher, size int, interval time.Duration) *Batcher {
+	return newBatcher(store, publisher, size, interval, size, 0)
+}
+
+func newBatcher(store StoreFunc, publisher events.Publisher, size int, interval time.Duration, queueSize int, retryDelay time.Duration) *Batcher {
+	if size <= 0 {
+		size = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &Batcher{
+		store:      store,
+		publisher:  publisher,
+		size:       size,
+		interval:   interval,
+		retryDelay: retryDelay,
+		messages:   make(chan Message, queueSize),
+		stopping:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func CopyStore(database *sql.DB) StoreFunc {
+	return func(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+		errs, err := db.CopyOrders(ctx, database, records)
+		if err == nil {
+			return errs, nil
+		}
+		if db.IsTransient(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("COPY of %d orders failed, retrying row by row: %v\n", len(records), err)
+		return db.AddOrders(ctx, database, records)
+	}
+}
+
+func (b *Batcher) Add(msg Message) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.closed {
+		select {
+		case b.messages <- msg:
+			return
+		case <-b.stopping:
+		}
+	}
+	log.Println("Ingest is shutting down, returning order for redelivery:", msg.Record.Order.OrderUID)
+	if err := msg.Retry(); err != nil {
+		log.Printf("Failed to return order %s for redelivery: %v\n", msg.Record.Order.OrderUID, err)
+	}
+}
+
+func (b *Batcher) Queued() int {
+	return len(b.messages)
+}
+
+func (b *Batcher) Close() {
+	close(b.stopping)
+	b.mu.Lock()
+	b.closed = true
+	close(b.messages)
+	b.mu.Unlock()
+	<-b.done
+}
+
+func (b *Batcher) Run() {
+	defer close(b.done)
+
+	batch := make([]Message, 0, b.size)
+	timer := time.NewTimer(b.interval)
+	timer.Stop()
+	timerRunning := false
+
+	flush := func() {
+		if timerRunning && !timer.Stop() {
+			<-timer.C
+		}
+		timerRunning = false
+		if len(batch) > 0 {
+			b.flush(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case msg, ok := <-b.messages:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, msg)
+			if len(batch) >= b.size {
+				flush()
+			} else if !timerRunning {
+				timer.Reset(b.interval)
+				timerRunning = true
+			}
+		case <-timer.C:
+			timerRunning = false
+			flush()
+		}
+	}
+}
+
+func (b *Batcher) retryAll(batch []Message) {
+	for _, msg := range batch {
+		if err := msg.Retry(); err != nil {
+			log.Printf("Failed to return order %s for redelivery: %v\n", msg.Record.Order.OrderUID, err)
+		}
+	}
+}
+
+func (b *Batcher) stopped() bool {
+	select {
+	case <-b.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Batcher) flush(batch []Message) {
+	records := make([]db.OrderRecord, len(batch))
+	for i, msg := range batch {
+		records[i] = msg.Record
+	}
+
+	if b.abandoned {
+		b.retryAll(batch)
+		return
+	}
+
+	start := time.Now()
+	errs, err := b.store(context.Background(), records)
+	for err != nil && b.retryDelay > 0 && !b.stopped() {
+		log.Printf("Failed to store batch of %d orders, retrying in %s: %v\n", len(batch), b.retryDelay, err)
+		select {
+		case <-b.stopping:
+		case <-time.After(b.retryDelay):
+			errs, err = b.store(context.Background(), records)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to store batch of %d orders, returning them for redelivery: %v\n", len(batch), err)
+		b.abandoned = b.retryDelay > 0 && b.stopped()
+		b.retryAll(batch)
+		return
+	}
+
+	stored := 0
+	for i, msg := range batch {
+		if errs != nil && errs[i] != nil {
+			if !db.IsUniqueViolation(errs[i]) {
+				log.Printf("Rejected order %s: %v\n", msg.Record.Order.OrderUID, errs[i])
+				if err := msg.Reject(); err != nil {
+					log.Printf("Failed to reject order %s: %v\n", msg.Record.Order.OrderUID, err)
+				}
+				continue
+			}
+			log.Println("Order already stored, acknowledging duplicate:", msg.Record.Order.OrderUID)
+		} else {
+			stored++
+			if b.publisher != nil {
+				b.publisher.Publish(events.OrderEvent{
+					Order:    msg.Record.Order,
+					Delivery: msg.Record.Delivery,
+					Payment:  msg.Record.Payment,
+					Items:    msg.Record.Items,
+				})
+			}
+		}
+		if msg.Ack != nil {
+			if err := msg.Ack(); err != nil {
+				log.Printf("Failed to acknowledge order %s: %v\n", msg.Record.Order.OrderUID, err)
+			}
+		}
+	}
+	log.Printf("Stored batch of %d/%d orders in %s\n", stored, len(batch), time.Since(start))
+}
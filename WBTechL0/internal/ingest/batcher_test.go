@@ -0,0 +1,202 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+)
+
+type recordingStore struct {
+	mu      sync.Mutex
+	batches [][]string
+	fail    error
+	reject  map[string]error
+}
+
+func (s *recordingStore) store(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	var uids []string
+	errs := make([]error, len(records))
+	for i, r := range records {
+		uids = append(uids, r.Order.OrderUID)
+		errs[i] = s.reject[r.Order.OrderUID]
+	}
+	s.batches = append(s.batches, uids)
+	return errs, nil
+}
+
+func (s *recordingStore) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sizes []int
+	for _, batch := range s.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func testMessage(uid string, acked *atomic.Int32) Message {
+	return Message{
+		Record: db.OrderRecord{Order: db.Order{OrderUID: uid}},
+		Ack: func() error {
+			acked.Add(1)
+			return nil
+		},
+	}
+}
+
+func TestBatcherFlushesOnSize(t *testing.T) {
+	store := &recordingStore{}
+	var acked atomic.Int32
+	b := NewBatcher(store.store, nil, 3, time.Hour)
+	go b.Run()
+
+	for _, uid := range []string{"a", "b", "c", "d", "e", "f"} {
+		b.Add(testMessage(uid, &acked))
+	}
+	deadline := time.Now().Add(time.Second)
+	for acked.Load() < 6 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	b.Close()
+
+	sizes := store.batchSizes()
+	if len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 3 {
+		t.Errorf("Expected two full batches, got %v", sizes)
+	}
+	if acked.Load() != 6 {
+		t.Errorf("Expected 6 acknowledgements, got %d", acked.Load())
+	}
+}
+
+func TestBatcherFlushesOnInterval(t *testing.T) {
+	store := &recordingStore{}
+	var acked atomic.Int32
+	b := NewBatcher(store.store, nil, 100, 20*time.Millisecond)
+	go b.Run()
+	defer b.Close()
+
+	b.Add(testMessage("a", &acked))
+	b.Add(testMessage("b", &acked))
+
+	deadline := time.Now().Add(time.Second)
+	for acked.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if sizes := store.batchSizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("Expected one partial batch after the interval, got %v", sizes)
+	}
+}
+
+func TestBatcherAcksOnlyAfterCommit(t *testing.T) {
+	store := &recordingStore{fail: errors.New("connection refused")}
+	var acked atomic.Int32
+	var published atomic.Int32
+	publisher := events.PublisherFunc(func(evt events.OrderEvent) { published.Add(1) })
+
+	b := NewBatcher(store.store, publisher, 2, time.Hour)
+	go b.Run()
+	b.Add(testMessage("a", &acked))
+	b.Add(testMessage("b", &acked))
+	b.Close()
+
+	if acked.Load() != 0 || published.Load() != 0 {
+		t.Errorf("Expected failed batch to stay unacknowledged, got %d acks and %d events", acked.Load(), published.Load())
+	}
+}
+
+func TestBatcherAcksRejectedRecords(t *testing.T) {
+	store := &recordingStore{reject: map[string]error{"b": errors.New("violates constraint")}}
+	var acked atomic.Int32
+	var published []string
+	publisher := events.PublisherFunc(func(evt events.OrderEvent) { published = append(published, evt.Order.OrderUID) })
+
+	b := NewBatcher(store.store, publisher, 2, time.Hour)
+	go b.Run()
+	b.Add(testMessage("a", &acked))
+	b.Add(testMessage("b", &acked))
+	b.Close()
+
+	if acked.Load() != 2 {
+		t.Errorf("Expected both messages to be acknowledged, got %d", acked.Load())
+	}
+	if len(published) != 1 || published[0] != "a" {
+		t.Errorf("Expected only the stored order to be published, got %v", published)
+	}
+}
+
+func TestBatcherNaksFailedBatchAndTermsRejects(t *testing.T) {
+	var acked, naked, termed atomic.Int32
+	withSettlement := func(uid string) Message {
+		msg := testMessage(uid, &acked)
+		msg.Nak = func() error {
+			naked.Add(1)
+			return nil
+		}
+		msg.Term = func() error {
+			termed.Add(1)
+			return nil
+		}
+		return msg
+	}
+
+	failing := &recordingStore{fail: errors.New("connection refused")}
+	b := NewBatcher(failing.store, nil, 2, time.Hour)
+	go b.Run()
+	b.Add(withSettlement("a"))
+	b.Add(withSettlement("b"))
+	b.Close()
+	if naked.Load() != 2 || acked.Load() != 0 || termed.Load() != 0 {
+		t.Errorf("Expected failed batch to be negatively acknowledged, got %d naks, %d acks, %d terms", naked.Load(), acked.Load(), termed.Load())
+	}
+
+	naked.Store(0)
+	rejecting := &recordingStore{reject: map[string]error{"b": errors.New("violates constraint")}}
+	b = NewBatcher(rejecting.store, nil, 2, time.Hour)
+	go b.Run()
+	b.Add(withSettlement("a"))
+	b.Add(withSettlement("b"))
+	b.Close()
+	if acked.Load() != 1 || termed.Load() != 1 || naked.Load() != 0 {
+		t.Errorf("Expected one ack and one termination, got %d acks, %d terms, %d naks", acked.Load(), termed.Load(), naked.Load())
+	}
+}
+
+func TestBatcherAddDuringClose(t *testing.T) {
+	store := &recordingStore{}
+	b := newBatcher(store.store, nil, 10, time.Hour, 1, 0)
+	go b.Run()
+
+	var acked, retried atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				msg := testMessage("a", &acked)
+				msg.Nak = func() error {
+					retried.Add(1)
+					return nil
+				}
+				b.Add(msg)
+			}
+		}()
+	}
+	b.Close()
+	wg.Wait()
+
+	if total := acked.Load() + retried.Load(); total != 800 {
+		t.Errorf("Expected every message to be acknowledged or returned, got %d acked and %d retried", acked.Load(), retried.Load())
+	}
+}
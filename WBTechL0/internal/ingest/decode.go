@@ -0,0 +1,43 @@
+package ingest
+
+import (
+	"log"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/message"
+)
+
+func DecodeRecord(d Delivery) (db.OrderRecord, error) {
+	return decodeRecord(d, false)
+}
+
+func DecodeStrictRecord(d Delivery) (db.OrderRecord, error) {
+	return decodeRecord(d, true)
+}
+
+func decodeRecord(d Delivery, strict bool) (db.OrderRecord, error) {
+	format, err := message.ParseFormat(d.Header("Content-Type"))
+	if err != nil {
+		return db.OrderRecord{}, err
+	}
+	order, err := message.DecodeAs(format, d.Data)
+	if err != nil {
+		return db.OrderRecord{}, err
+	}
+	record, err := order.Record()
+	if err != nil {
+		return db.OrderRecord{}, err
+	}
+	if strict {
+		if err := order.CheckRequired(); err != nil {
+			return db.OrderRecord{}, err
+		}
+	}
+	if err := order.CheckPayment(); err != nil {
+		if strict {
+			return db.OrderRecord{}, err
+		}
+		log.Printf("Storing order %s with inconsistent payment: %v\n", order.OrderUID, err)
+	}
+	return record, nil
+}
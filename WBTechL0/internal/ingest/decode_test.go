@@ -0,0 +1,48 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+
+	"WBTechL0/internal/message"
+)
+
+func TestDecodeRecord(t *testing.T) {
+	for _, data := range []string{`{not json`, `{"order_uid":""}`} {
+		if _, err := DecodeRecord(Delivery{Data: []byte(data)}); err == nil {
+			t.Errorf("Expected %s to fail decoding", data)
+		}
+	}
+
+	order := message.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		DateCreated: "2021-11-26T06:22:19Z",
+		Payment:     message.Payment{Transaction: "b563feb7b2b84b6test", Currency: "USD"},
+	}
+	data, err := message.EncodeAs(message.FormatMsgPack, order)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	record, err := DecodeRecord(Delivery{Data: data, Headers: map[string]string{"content-type": message.ContentTypeMsgPack}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if record.Order.OrderUID != order.OrderUID {
+		t.Errorf("Expected order %s, got %s", order.OrderUID, record.Order.OrderUID)
+	}
+	if _, err := DecodeRecord(Delivery{Data: data, Headers: map[string]string{"Content-Type": "text/plain"}}); err == nil {
+		t.Error("Expected an unsupported content type to fail decoding")
+	}
+
+	order.Payment.Amount = 100
+	data, err = message.EncodeAs(message.FormatJSON, order)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := DecodeRecord(Delivery{Data: data}); err != nil {
+		t.Errorf("Expected an inconsistent payment to be stored, got %v", err)
+	}
+	if _, err := DecodeStrictRecord(Delivery{Data: data}); !errors.Is(err, message.ErrInvalidOrder) {
+		t.Errorf("Expected strict decoding to reject an inconsistent payment, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"WBTechL0/internal/events"
+)
+
+type Processor interface {
+	Add(msg Message)
+	Run()
+	Close()
+}
+
+type Pool struct {
+	workers []*Batcher
+}
+
+func NewPool(workers, queueSize int, store StoreFunc, publisher events.Publisher, batchSize int, batchInterval, retryDelay time.Duration) *Pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	p := &Pool{workers: make([]*Batcher, workers)}
+	for i := range p.workers {
+		p.workers[i] = newBatcher(store, publisher, batchSize, batchInterval, queueSize, retryDelay)
+	}
+	return p
+}
+
+func (p *Pool) Add(msg Message) {
+	p.workers[partition(msg.Record.Order.OrderUID, len(p.workers))].Add(msg)
+}
+
+func (p *Pool) Run() {
+	var wg sync.WaitGroup
+	for _, w := range p.workers {
+		wg.Add(1)
+		go func(w *Batcher) {
+			defer wg.Done()
+			w.Run()
+		}(w)
+	}
+	wg.Wait()
+}
+
+func (p *Pool) Close() {
+	for _, w := range p.workers {
+		w.Close()
+	}
+}
+
+func (p *Pool) Workers() int {
+	return len(p.workers)
+}
+
+func (p *Pool) Queued() int {
+	total := 0
+	for _, w := range p.workers {
+		total += w.Queued()
+	}
+	return total
+}
+
+func partition(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
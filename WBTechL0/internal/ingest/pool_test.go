@@ -0,0 +1,155 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+)
+
+func TestPoolPreservesPerOrderOrdering(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	store := func(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range records {
+			seen[r.Order.OrderUID] = append(seen[r.Order.OrderUID], r.Order.SMID)
+		}
+		return nil, nil
+	}
+
+	pool := NewPool(4, 8, store, nil, 3, time.Millisecond, time.Millisecond)
+	go pool.Run()
+	for seq := 0; seq < 50; seq++ {
+		for uid := 0; uid < 10; uid++ {
+			pool.Add(Message{Record: db.OrderRecord{Order: db.Order{OrderUID: fmt.Sprintf("uid-%d", uid), SMID: seq}}})
+		}
+	}
+	pool.Close()
+
+	for uid, seqs := range seen {
+		if len(seqs) != 50 {
+			t.Errorf("Expected 50 records for %s, got %d", uid, len(seqs))
+		}
+		for i := range seqs {
+			if seqs[i] != i {
+				t.Errorf("Records for %s stored out of order: %v", uid, seqs)
+				break
+			}
+		}
+	}
+}
+
+func TestPoolSlowPartitionDoesNotBlockOthers(t *testing.T) {
+	slow := "slow"
+	fast := "fast"
+	for partition(fast, 2) == partition(slow, 2) {
+		fast += "x"
+	}
+
+	release := make(chan struct{})
+	store := func(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+		if records[0].Order.OrderUID == slow {
+			<-release
+		}
+		return nil, nil
+	}
+
+	var slowAcked, fastAcked atomic.Bool
+	pool := NewPool(2, 1, store, nil, 1, time.Millisecond, time.Millisecond)
+	go pool.Run()
+	pool.Add(Message{Record: db.OrderRecord{Order: db.Order{OrderUID: slow}}, Ack: func() error { slowAcked.Store(true); return nil }})
+	pool.Add(Message{Record: db.OrderRecord{Order: db.Order{OrderUID: fast}}, Ack: func() error { fastAcked.Store(true); return nil }})
+
+	deadline := time.Now().Add(time.Second)
+	for !fastAcked.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !fastAcked.Load() {
+		t.Error("Expected the fast partition to finish while the slow one is blocked")
+	}
+	if slowAcked.Load() {
+		t.Error("Expected the slow message not to be acknowledged before its worker finishes")
+	}
+
+	close(release)
+	pool.Close()
+	if !slowAcked.Load() {
+		t.Error("Expected the slow message to be acknowledged once its worker finished")
+	}
+}
+
+func TestPoolRetriesFailedBatchBeforeLaterMessages(t *testing.T) {
+	var mu sync.Mutex
+	var stored []int
+	failures := 2
+	store := func(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			return nil, errors.New("connection refused")
+		}
+		for _, r := range records {
+			stored = append(stored, r.Order.SMID)
+		}
+		return nil, nil
+	}
+
+	var naked atomic.Int32
+	pool := NewPool(1, 8, store, nil, 1, time.Millisecond, time.Millisecond)
+	go pool.Run()
+	for seq := 0; seq < 3; seq++ {
+		pool.Add(Message{
+			Record: db.OrderRecord{Order: db.Order{OrderUID: "a", SMID: seq}},
+			Nak:    func() error { naked.Add(1); return nil },
+		})
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		done := len(stored) == 3
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	pool.Close()
+
+	if naked.Load() != 0 {
+		t.Errorf("Expected the failed batch to be retried in place, got %d naks", naked.Load())
+	}
+	if len(stored) != 3 || stored[0] != 0 || stored[1] != 1 || stored[2] != 2 {
+		t.Errorf("Expected records stored in order, got %v", stored)
+	}
+}
+
+func TestPoolReturnsQueuedMessagesAfterAbandonedBatch(t *testing.T) {
+	var stores atomic.Int32
+	store := func(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+		stores.Add(1)
+		return nil, errors.New("connection refused")
+	}
+
+	var naked atomic.Int32
+	pool := NewPool(1, 8, store, nil, 1, time.Millisecond, time.Hour)
+	for seq := 0; seq < 3; seq++ {
+		pool.Add(Message{
+			Record: db.OrderRecord{Order: db.Order{OrderUID: "a", SMID: seq}},
+			Nak:    func() error { naked.Add(1); return nil },
+		})
+	}
+	go pool.Run()
+	pool.Close()
+
+	if naked.Load() != 3 || stores.Load() != 1 {
+		t.Errorf("Expected all messages returned after a single failed attempt, got %d naks and %d stores", naked.Load(), stores.Load())
+	}
+}
@@ -0,0 +1,214 @@
+package ingest
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"WBTechL0/internal/db"
+)
+
+type Delivery struct {
+	ID      string
+	Data    []byte
+	Headers map[string]string
+	Ref     any
+}
+
+func (d Delivery) Header(key string) string {
+	if v, ok := d.Headers[key]; ok {
+		return v
+	}
+	for k, v := range d.Headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+type Source interface {
+	Start(ctx context.Context, handle func(Delivery)) error
+	Ack(d Delivery) error
+	Nack(d Delivery, retry bool) error
+}
+
+type Monitor interface {
+	Ready(ctx context.Context) error
+	Reconnects() uint64
+}
+
+type DecodeFunc func(d Delivery) (db.OrderRecord, error)
+
+type Ingestor struct {
+	source    Source
+	decode    DecodeFunc
+	processor Processor
+}
+
+func NewIngestor(source Source, decode DecodeFunc, processor Processor) *Ingestor {
+	return &Ingestor{source: source, decode: decode, processor: processor}
+}
+
+func (i *Ingestor) Run(ctx context.Context) error {
+	go i.processor.Run()
+	err := i.source.Start(ctx, i.handle)
+	if closer, ok := i.source.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	i.processor.Close()
+	return err
+}
+
+func (i *Ingestor) handle(d Delivery) {
+	record, err := i.decode(d)
+	if err != nil {
+		log.Printf("Dropping undecodable message %s: %v\n", d.ID, err)
+		if err := i.source.Nack(d, false); err != nil {
+			log.Printf("Failed to reject message %s: %v\n", d.ID, err)
+		}
+		return
+	}
+
+	i.processor.Add(Message{
+		Record: record,
+		Ack: func() error {
+			return i.source.Ack(d)
+		},
+		Nak: func() error {
+			return i.source.Nack(d, true)
+		},
+		Term: func() error {
+			return i.source.Nack(d, false)
+		},
+	})
+}
+
+func (i *Ingestor) Ready(ctx context.Context) error {
+	if m, ok := i.source.(Monitor); ok {
+		return m.Ready(ctx)
+	}
+	return nil
+}
+
+func (i *Ingestor) Reconnects() uint64 {
+	if m, ok := i.source.(Monitor); ok {
+		return m.Reconnects()
+	}
+	return 0
+}
+
+type Redeliverer struct {
+	delay   time.Duration
+	mu      sync.Mutex
+	ctx     context.Context
+	handle  func(Delivery)
+	timers  map[*time.Timer]struct{}
+	stopped bool
+	running sync.WaitGroup
+}
+
+func NewRedeliverer(delay time.Duration) *Redeliverer {
+	return &Redeliverer{delay: delay, timers: make(map[*time.Timer]struct{})}
+}
+
+func (r *Redeliverer) Bind(ctx context.Context, handle func(Delivery)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+	r.handle = handle
+	r.stopped = false
+}
+
+func (r *Redeliverer) Redeliver(d Delivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handle == nil || r.stopped {
+		return
+	}
+	ctx, handle := r.ctx, r.handle
+	var timer *time.Timer
+	r.running.Add(1)
+	timer = time.AfterFunc(r.delay, func() {
+		defer r.running.Done()
+		r.mu.Lock()
+		_, pending := r.timers[timer]
+		delete(r.timers, timer)
+		r.mu.Unlock()
+		if pending && ctx.Err() == nil {
+			handle(d)
+		}
+	})
+	r.timers[timer] = struct{}{}
+}
+
+func (r *Redeliverer) Stop() {
+	r.mu.Lock()
+	r.stopped = true
+	for timer := range r.timers {
+		if timer.Stop() {
+			r.running.Done()
+		}
+		delete(r.timers, timer)
+	}
+	r.mu.Unlock()
+	r.running.Wait()
+}
+
+type CommitTracker struct {
+	mu      sync.Mutex
+	pending []trackedOffset
+}
+
+type trackedOffset struct {
+	offset int64
+	done   bool
+}
+
+func (t *CommitTracker) Add(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, trackedOffset{offset: offset})
+}
+
+func (t *CommitTracker) Done(offset int64) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.pending {
+		if t.pending[i].offset == offset {
+			t.pending[i].done = true
+			break
+		}
+	}
+
+	n := 0
+	for n < len(t.pending) && t.pending[n].done {
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	committed := t.pending[n-1].offset
+	t.pending = t.pending[n:]
+	return committed, true
+}
+
+func (t *CommitTracker) Last() (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) == 0 {
+		return 0, false
+	}
+	return t.pending[len(t.pending)-1].offset, true
+}
+
+func (t *CommitTracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
@@ -0,0 +1,162 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+)
+
+type fakeSource struct {
+	deliveries []Delivery
+	mu         sync.Mutex
+	acked      []string
+	retried    []string
+	rejected   []string
+}
+
+func (s *fakeSource) Start(ctx context.Context, handle func(Delivery)) error {
+	for _, d := range s.deliveries {
+		handle(d)
+	}
+	return nil
+}
+
+func (s *fakeSource) Ack(d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acked = append(s.acked, d.ID)
+	return nil
+}
+
+func (s *fakeSource) Nack(d Delivery, retry bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if retry {
+		s.retried = append(s.retried, d.ID)
+	} else {
+		s.rejected = append(s.rejected, d.ID)
+	}
+	return nil
+}
+
+func decodeUID(d Delivery) (db.OrderRecord, error) {
+	if string(d.Data) == "" {
+		return db.OrderRecord{}, errors.New("empty payload")
+	}
+	return db.OrderRecord{Order: db.Order{OrderUID: string(d.Data)}}, nil
+}
+
+func TestIngestorSettlesDeliveries(t *testing.T) {
+	source := &fakeSource{deliveries: []Delivery{
+		{ID: "1", Data: []byte("a")},
+		{ID: "2", Data: nil},
+		{ID: "3", Data: []byte("b")},
+	}}
+	store := &recordingStore{reject: map[string]error{"b": errors.New("violates constraint")}}
+
+	ingestor := NewIngestor(source, decodeUID, NewBatcher(store.store, nil, 10, time.Hour))
+	if err := ingestor.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(source.acked) != 1 || source.acked[0] != "1" {
+		t.Errorf("Expected only delivery 1 to be acknowledged, got %v", source.acked)
+	}
+	if len(source.rejected) != 2 || source.rejected[0] != "2" || source.rejected[1] != "3" {
+		t.Errorf("Expected deliveries 2 and 3 to be rejected, got %v", source.rejected)
+	}
+	if err := ingestor.Ready(context.Background()); err != nil {
+		t.Errorf("Expected a source without a monitor to be ready, got %v", err)
+	}
+}
+
+func TestIngestorRetriesFailedBatch(t *testing.T) {
+	source := &fakeSource{deliveries: []Delivery{{ID: "1", Data: []byte("a")}}}
+	store := &recordingStore{fail: errors.New("connection refused")}
+
+	ingestor := NewIngestor(source, decodeUID, NewBatcher(store.store, nil, 10, time.Hour))
+	if err := ingestor.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(source.retried) != 1 || len(source.acked) != 0 {
+		t.Errorf("Expected the delivery to be retried, got acked=%v retried=%v", source.acked, source.retried)
+	}
+}
+
+type closingSource struct {
+	fakeSource
+	closed *[]string
+}
+
+func (s *closingSource) Close() error {
+	*s.closed = append(*s.closed, "source")
+	return nil
+}
+
+type closingProcessor struct {
+	closed *[]string
+}
+
+func (p closingProcessor) Add(msg Message) {}
+func (p closingProcessor) Run()            {}
+func (p closingProcessor) Close()          { *p.closed = append(*p.closed, "processor") }
+
+func TestIngestorClosesSourceBeforeProcessor(t *testing.T) {
+	var closed []string
+	ingestor := NewIngestor(&closingSource{closed: &closed}, decodeUID, closingProcessor{closed: &closed})
+	if err := ingestor.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 2 || closed[0] != "source" || closed[1] != "processor" {
+		t.Errorf("Expected the source to be closed before the processor, got %v", closed)
+	}
+}
+
+func TestCommitTrackerCommitsContiguousPrefix(t *testing.T) {
+	var tracker CommitTracker
+	for _, offset := range []int64{10, 11, 13, 14} {
+		tracker.Add(offset)
+	}
+
+	if _, ok := tracker.Done(11); ok {
+		t.Error("Expected no commit while offset 10 is pending")
+	}
+	if offset, ok := tracker.Done(10); !ok || offset != 11 {
+		t.Errorf("Expected commit up to 11, got %d, %t", offset, ok)
+	}
+	if _, ok := tracker.Done(14); ok {
+		t.Error("Expected no commit while offset 13 is pending")
+	}
+	if offset, ok := tracker.Done(13); !ok || offset != 14 {
+		t.Errorf("Expected commit up to 14, got %d, %t", offset, ok)
+	}
+	if tracker.Pending() != 0 {
+		t.Errorf("Expected no pending offsets, got %d", tracker.Pending())
+	}
+}
+
+func TestRedelivererStopCancelsPendingTimers(t *testing.T) {
+	r := NewRedeliverer(20 * time.Millisecond)
+	var mu sync.Mutex
+	var handled []string
+	r.Bind(context.Background(), func(d Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, d.ID)
+	})
+
+	r.Redeliver(Delivery{ID: "1"})
+	r.Stop()
+	r.Redeliver(Delivery{ID: "2"})
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 0 {
+		t.Errorf("Expected no redeliveries after Stop, got %v", handled)
+	}
+}
@@ -0,0 +1,31 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+	"WBTechL0/internal/message"
+)
+
+func StoreOrder(ctx context.Context, database *sql.DB, publisher events.Publisher, order message.Order) (events.OrderEvent, error) {
+	evt, err := order.Event()
+	if err != nil {
+		return events.OrderEvent{}, err
+	}
+
+	log.Printf("OrderUID for delivery before insert: %s\n", evt.Delivery.OrderUID)
+
+	err = db.AddOrder(ctx, database, evt.Order, evt.Delivery, evt.Payment, evt.Items)
+	if err != nil {
+		return events.OrderEvent{}, err
+	}
+
+	log.Println("Order successfully added to database:", evt.Order.OrderUID)
+	if publisher != nil {
+		publisher.Publish(evt)
+	}
+	return evt, nil
+}
@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ingest"
+	"github.com/segmentio/kafka-go"
+)
+
+var ErrNotStarted = errors.New("kafka source is not consuming")
+
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type Source struct {
+	reader    Reader
+	redeliver *ingest.Redeliverer
+	consuming atomic.Bool
+	mu        sync.Mutex
+	trackers  map[int]*ingest.CommitTracker
+}
+
+func NewSource(cfg config.KafkaConfig, retryDelay time.Duration) *Source {
+	return NewSourceWithReader(kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  cfg.Brokers,
+		GroupID:  cfg.GroupID,
+		Topic:    cfg.Topic,
+		MinBytes: 1,
+		MaxBytes: 10e6,
+	}), retryDelay)
+}
+
+func NewSourceWithReader(reader Reader, retryDelay time.Duration) *Source {
+	return &Source{
+		reader:    reader,
+		redeliver: ingest.NewRedeliverer(retryDelay),
+		trackers:  make(map[int]*ingest.CommitTracker),
+	}
+}
+
+func (s *Source) Start(ctx context.Context, handle func(ingest.Delivery)) error {
+	s.redeliver.Bind(ctx, handle)
+	defer s.redeliver.Stop()
+	s.consuming.Store(true)
+	defer s.consuming.Store(false)
+
+	for {
+		msg, err := s.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("fetching kafka message: %w", err)
+		}
+
+		s.track(msg)
+		handle(delivery(msg))
+	}
+}
+
+func delivery(msg kafka.Message) ingest.Delivery {
+	d := ingest.Delivery{
+		ID:   fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset),
+		Data: msg.Value,
+		Ref:  msg,
+	}
+	if len(msg.Headers) > 0 {
+		d.Headers = make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			d.Headers[h.Key] = string(h.Value)
+		}
+	}
+	return d
+}
+
+func (s *Source) tracker(partition int) *ingest.CommitTracker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.trackers[partition]
+	if !ok {
+		t = &ingest.CommitTracker{}
+		s.trackers[partition] = t
+	}
+	return t
+}
+
+func (s *Source) Ack(d ingest.Delivery) error {
+	return s.commit(d.Ref.(kafka.Message))
+}
+
+func (s *Source) Nack(d ingest.Delivery, retry bool) error {
+	if retry {
+		s.redeliver.Redeliver(d)
+		return nil
+	}
+	log.Println("Skipping poison kafka message:", d.ID)
+	return s.commit(d.Ref.(kafka.Message))
+}
+
+func (s *Source) track(msg kafka.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.trackers[msg.Partition]
+	if ok {
+		if last, pending := t.Last(); pending && msg.Offset <= last {
+			log.Printf("Kafka partition %d was reassigned at offset %d, dropping %d pending offsets\n", msg.Partition, msg.Offset, t.Pending())
+			ok = false
+		}
+	}
+	if !ok {
+		t = &ingest.CommitTracker{}
+		s.trackers[msg.Partition] = t
+	}
+	t.Add(msg.Offset)
+}
+
+func (s *Source) commit(msg kafka.Message) error {
+	offset, ok := s.tracker(msg.Partition).Done(msg.Offset)
+	if !ok {
+		return nil
+	}
+	msg.Offset = offset
+	return s.reader.CommitMessages(context.Background(), msg)
+}
+
+func (s *Source) Ready(ctx context.Context) error {
+	if !s.consuming.Load() {
+		return ErrNotStarted
+	}
+	return nil
+}
+
+func (s *Source) Reconnects() uint64 {
+	return 0
+}
+
+func (s *Source) Close() error {
+	return s.reader.Close()
+}
@@ -0,0 +1,223 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/ingest"
+	"github.com/segmentio/kafka-go"
+)
+
+type fakeBroker struct {
+	mu        sync.Mutex
+	pending   []kafka.Message
+	committed map[int]int64
+	closed    bool
+	notify    chan struct{}
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{committed: make(map[int]int64), notify: make(chan struct{}, 1)}
+}
+
+func (b *fakeBroker) produce(partition int, offset int64, value string) {
+	b.mu.Lock()
+	b.pending = append(b.pending, kafka.Message{
+		Topic:     "orders",
+		Partition: partition,
+		Offset:    offset,
+		Value:     []byte(value),
+		Headers:   []kafka.Header{{Key: "content-type", Value: []byte("application/json")}},
+	})
+	b.mu.Unlock()
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (b *fakeBroker) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return kafka.Message{}, io.EOF
+		}
+		if len(b.pending) > 0 {
+			msg := b.pending[0]
+			b.pending = b.pending[1:]
+			b.mu.Unlock()
+			return msg, nil
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-b.notify:
+		}
+	}
+}
+
+func (b *fakeBroker) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, msg := range msgs {
+		if msg.Offset+1 <= b.committed[msg.Partition] {
+			return errors.New("commit moved backwards")
+		}
+		b.committed[msg.Partition] = msg.Offset + 1
+	}
+	return nil
+}
+
+func (b *fakeBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *fakeBroker) committedOffset(partition int) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.committed[partition]
+}
+
+func collect(t *testing.T, source *Source, n int) (chan ingest.Delivery, context.CancelFunc) {
+	t.Helper()
+	deliveries := make(chan ingest.Delivery, n)
+	ctx, cancel := context.WithCancel(context.Background())
+	go source.Start(ctx, func(d ingest.Delivery) { deliveries <- d })
+	return deliveries, cancel
+}
+
+func next(t *testing.T, deliveries chan ingest.Delivery) ingest.Delivery {
+	t.Helper()
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a delivery")
+	}
+	return ingest.Delivery{}
+}
+
+func TestSourceCommitsContiguousOffsets(t *testing.T) {
+	broker := newFakeBroker()
+	source := NewSourceWithReader(broker, time.Millisecond)
+	deliveries, cancel := collect(t, source, 10)
+	defer cancel()
+
+	broker.produce(0, 0, "a")
+	broker.produce(0, 1, "b")
+	broker.produce(1, 0, "c")
+	a, b, c := next(t, deliveries), next(t, deliveries), next(t, deliveries)
+	if a.ID != "orders/0/0" || a.Headers["content-type"] != "application/json" {
+		t.Errorf("Unexpected delivery %+v", a)
+	}
+
+	source.Ack(b)
+	if got := broker.committedOffset(0); got != 0 {
+		t.Errorf("Expected no commit on partition 0 while offset 0 is pending, got %d", got)
+	}
+	source.Ack(c)
+	if got := broker.committedOffset(1); got != 1 {
+		t.Errorf("Expected partition 1 committed to 1, got %d", got)
+	}
+	source.Nack(a, false)
+	if got := broker.committedOffset(0); got != 2 {
+		t.Errorf("Expected partition 0 committed to 2 after skipping the poison message, got %d", got)
+	}
+}
+
+func TestSourceResetsTrackerOnReassignment(t *testing.T) {
+	broker := newFakeBroker()
+	source := NewSourceWithReader(broker, time.Millisecond)
+	deliveries, cancel := collect(t, source, 10)
+	defer cancel()
+
+	broker.produce(0, 3, "a")
+	broker.produce(0, 4, "b")
+	next(t, deliveries)
+	next(t, deliveries)
+
+	broker.produce(0, 3, "a")
+	again := next(t, deliveries)
+	source.Ack(again)
+	if got := broker.committedOffset(0); got != 4 {
+		t.Errorf("Expected partition 0 committed to 4 after reassignment, got %d", got)
+	}
+}
+
+func TestSourceRedeliversRetriedMessages(t *testing.T) {
+	broker := newFakeBroker()
+	source := NewSourceWithReader(broker, time.Millisecond)
+	deliveries, cancel := collect(t, source, 10)
+	defer cancel()
+
+	broker.produce(0, 5, "a")
+	first := next(t, deliveries)
+	source.Nack(first, true)
+
+	again := next(t, deliveries)
+	if again.ID != first.ID {
+		t.Errorf("Expected %s to be redelivered, got %s", first.ID, again.ID)
+	}
+	if got := broker.committedOffset(0); got != 0 {
+		t.Errorf("Expected no commit before the retry succeeds, got %d", got)
+	}
+	source.Ack(again)
+	if got := broker.committedOffset(0); got != 6 {
+		t.Errorf("Expected partition 0 committed to 6, got %d", got)
+	}
+}
+
+func TestIngestorWithKafkaSource(t *testing.T) {
+	broker := newFakeBroker()
+	source := NewSourceWithReader(broker, time.Millisecond)
+
+	var mu sync.Mutex
+	var stored []string
+	store := func(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range records {
+			stored = append(stored, r.Order.OrderUID)
+		}
+		return nil, nil
+	}
+	decode := func(d ingest.Delivery) (db.OrderRecord, error) {
+		return db.OrderRecord{Order: db.Order{OrderUID: string(d.Data)}}, nil
+	}
+	ingestor := ingest.NewIngestor(source, decode, ingest.NewBatcher(store, nil, 2, time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ingestor.Run(ctx) }()
+
+	broker.produce(0, 0, "a")
+	broker.produce(0, 1, "b")
+	deadline := time.Now().Add(time.Second)
+	for broker.committedOffset(0) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if broker.committedOffset(0) != 2 {
+		t.Errorf("Expected both messages committed, got offset %d", broker.committedOffset(0))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(stored) != 2 {
+		t.Errorf("Expected two stored orders, got %v", stored)
+	}
+}
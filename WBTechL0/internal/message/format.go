@@ -0,0 +1,278 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"WBTechL0/internal/message/orderpb"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatProtobuf
+	FormatMsgPack
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeMsgPack  = "application/msgpack"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported payload format")
+
+var formatsByMediaType = map[string]Format{
+	"application/json":                FormatJSON,
+	"text/json":                       FormatJSON,
+	"application/x-protobuf":          FormatProtobuf,
+	"application/protobuf":            FormatProtobuf,
+	"application/vnd.google.protobuf": FormatProtobuf,
+	"application/msgpack":             FormatMsgPack,
+	"application/x-msgpack":           FormatMsgPack,
+	"application/vnd.msgpack":         FormatMsgPack,
+}
+
+func ParseFormat(contentType string) (Format, error) {
+	if contentType == "" {
+		return FormatJSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedFormat, contentType)
+	}
+	if format, ok := formatsByMediaType[mediaType]; ok {
+		return format, nil
+	}
+	if strings.HasSuffix(mediaType, "+json") {
+		return FormatJSON, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnsupportedFormat, contentType)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatProtobuf:
+		return ContentTypeProtobuf
+	case FormatMsgPack:
+		return ContentTypeMsgPack
+	}
+	return ContentTypeJSON
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatProtobuf:
+		return "protobuf"
+	case FormatMsgPack:
+		return "msgpack"
+	}
+	return "json"
+}
+
+func DecodeAs(format Format, data []byte) (Order, error) {
+	switch format {
+	case FormatJSON:
+		return Decode(data)
+	case FormatProtobuf:
+		return decodeProtobuf(data)
+	case FormatMsgPack:
+		return decodeMsgPack(data)
+	}
+	return Order{}, fmt.Errorf("%w: %d", ErrUnsupportedFormat, format)
+}
+
+func EncodeAs(format Format, order Order) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return Encode(order)
+	case FormatProtobuf:
+		return proto.Marshal(toProto(order))
+	case FormatMsgPack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(order); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnsupportedFormat, format)
+}
+
+func Wrap(format Format, payload []byte) ([]byte, error) {
+	if format == FormatJSON {
+		return payload, nil
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{
+		SchemaVersion: CurrentVersion,
+		ContentType:   format.ContentType(),
+		Payload:       encoded,
+	})
+}
+
+func decodeMsgPack(data []byte) (Order, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+	var order Order
+	if err := dec.Decode(&order); err != nil {
+		return Order{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return order, nil
+}
+
+func decodeProtobuf(data []byte) (Order, error) {
+	var pb orderpb.Order
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return Order{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if hasUnknownFields(pb.ProtoReflect()) {
+		return Order{}, fmt.Errorf("%w: unknown protobuf fields", ErrMalformed)
+	}
+	return fromProto(&pb), nil
+}
+
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+	unknown := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Message() == nil:
+		case fd.IsList():
+			for i := 0; i < v.List().Len() && !unknown; i++ {
+				unknown = hasUnknownFields(v.List().Get(i).Message())
+			}
+		default:
+			unknown = hasUnknownFields(v.Message())
+		}
+		return !unknown
+	})
+	return unknown
+}
+
+func toProto(o Order) *orderpb.Order {
+	items := make([]*orderpb.Item, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = &orderpb.Item{
+			ChrtId:      int64(item.ChrtID),
+			TrackNumber: item.TrackNumber,
+			Price:       int64(item.Price),
+			Rid:         item.RID,
+			Name:        item.Name,
+			Sale:        int64(item.Sale),
+			Size:        item.Size,
+			TotalPrice:  int64(item.TotalPrice),
+			NmId:        int64(item.NMID),
+			Brand:       item.Brand,
+			Status:      int64(item.Status),
+		}
+	}
+	return &orderpb.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &orderpb.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &orderpb.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    int64(o.Payment.PaymentDt),
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.ShardKey,
+		SmId:              int64(o.SMID),
+		DateCreated:       string(o.DateCreated),
+		OofShard:          o.OOFShard,
+	}
+}
+
+func fromProto(pb *orderpb.Order) Order {
+	var items []Item
+	if len(pb.GetItems()) > 0 {
+		items = make([]Item, len(pb.GetItems()))
+	}
+	for i, item := range pb.GetItems() {
+		items[i] = Item{
+			ChrtID:      int(item.GetChrtId()),
+			TrackNumber: item.GetTrackNumber(),
+			Price:       int(item.GetPrice()),
+			RID:         item.GetRid(),
+			Name:        item.GetName(),
+			Sale:        int(item.GetSale()),
+			Size:        item.GetSize(),
+			TotalPrice:  int(item.GetTotalPrice()),
+			NMID:        int(item.GetNmId()),
+			Brand:       item.GetBrand(),
+			Status:      int(item.GetStatus()),
+		}
+	}
+	delivery, payment := pb.GetDelivery(), pb.GetPayment()
+	return Order{
+		OrderUID:    pb.GetOrderUid(),
+		TrackNumber: pb.GetTrackNumber(),
+		Entry:       pb.GetEntry(),
+		Delivery: Delivery{
+			Name:    delivery.GetName(),
+			Phone:   delivery.GetPhone(),
+			Zip:     delivery.GetZip(),
+			City:    delivery.GetCity(),
+			Address: delivery.GetAddress(),
+			Region:  delivery.GetRegion(),
+			Email:   delivery.GetEmail(),
+		},
+		Payment: Payment{
+			Transaction:  payment.GetTransaction(),
+			RequestID:    payment.GetRequestId(),
+			Currency:     payment.GetCurrency(),
+			Provider:     payment.GetProvider(),
+			Amount:       int(payment.GetAmount()),
+			PaymentDt:    int(payment.GetPaymentDt()),
+			Bank:         payment.GetBank(),
+			DeliveryCost: int(payment.GetDeliveryCost()),
+			GoodsTotal:   int(payment.GetGoodsTotal()),
+			CustomFee:    int(payment.GetCustomFee()),
+		},
+		Items:             items,
+		Locale:            pb.GetLocale(),
+		InternalSignature: pb.GetInternalSignature(),
+		CustomerID:        pb.GetCustomerId(),
+		DeliveryService:   pb.GetDeliveryService(),
+		ShardKey:          pb.GetShardkey(),
+		SMID:              int(pb.GetSmId()),
+		DateCreated:       Date(pb.GetDateCreated()),
+		OOFShard:          pb.GetOofShard(),
+	}
+}
@@ -0,0 +1,103 @@
+package message
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseFormat(t *testing.T) {
+	cases := map[string]Format{
+		"":                                FormatJSON,
+		"application/json; charset=utf-8": FormatJSON,
+		"application/vnd.order.v2+json":   FormatJSON,
+		"application/x-protobuf":          FormatProtobuf,
+		"application/protobuf":            FormatProtobuf,
+		"application/msgpack":             FormatMsgPack,
+		"Application/X-MsgPack":           FormatMsgPack,
+	}
+	for contentType, expected := range cases {
+		format, err := ParseFormat(contentType)
+		if err != nil || format != expected {
+			t.Errorf("Content type %q: expected %v, got %v (%v)", contentType, expected, format, err)
+		}
+	}
+
+	for _, contentType := range []string{"text/plain", "application/xml", ";;"} {
+		if _, err := ParseFormat(contentType); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Content type %q: expected %v, got %v", contentType, ErrUnsupportedFormat, err)
+		}
+	}
+}
+
+func TestFormatsRoundTrip(t *testing.T) {
+	order, err := Decode([]byte(legacyOrder))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, format := range []Format{FormatJSON, FormatProtobuf, FormatMsgPack} {
+		data, err := EncodeAs(format, order)
+		if err != nil {
+			t.Fatalf("Format %s: expected no error, got %v", format, err)
+		}
+		decoded, err := DecodeAs(format, data)
+		if err != nil {
+			t.Fatalf("Format %s: expected no error, got %v", format, err)
+		}
+		if !reflect.DeepEqual(decoded, order) {
+			t.Errorf("Format %s: expected %+v, got %+v", format, order, decoded)
+		}
+
+		wrapped, err := Wrap(format, data)
+		if err != nil {
+			t.Fatalf("Format %s: expected no error, got %v", format, err)
+		}
+		unwrapped, err := Decode(wrapped)
+		if err != nil {
+			t.Fatalf("Format %s: expected no error decoding envelope, got %v", format, err)
+		}
+		if !reflect.DeepEqual(unwrapped, order) {
+			t.Errorf("Format %s: expected %+v from envelope, got %+v", format, order, unwrapped)
+		}
+	}
+}
+
+func TestBinaryFormatsAreStrict(t *testing.T) {
+	order, err := Decode([]byte(legacyOrder))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, err := EncodeAs(FormatProtobuf, order)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data = protowire.AppendTag(data, 99, protowire.VarintType)
+	data = protowire.AppendVarint(data, 1)
+	if _, err := DecodeAs(FormatProtobuf, data); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected %v for unknown protobuf field, got %v", ErrMalformed, err)
+	}
+
+	data, err = msgpack.Marshal(map[string]any{"order_uid": "x", "colour": "red"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := DecodeAs(FormatMsgPack, data); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected %v for unknown msgpack field, got %v", ErrMalformed, err)
+	}
+
+	cases := map[string]error{
+		`{"schema_version":1,"content_type":"application/msgpack","payload":"gA=="}`: ErrUnsupportedVersion,
+		`{"schema_version":2,"content_type":"application/msgpack","payload":{}}`:     ErrMalformed,
+		`{"schema_version":2,"content_type":"text/plain","payload":"gA=="}`:          ErrUnsupportedFormat,
+	}
+	for data, expected := range cases {
+		if _, err := Decode([]byte(data)); !errors.Is(err, expected) {
+			t.Errorf("Message %s: expected %v, got %v", data, expected, err)
+		}
+	}
+}
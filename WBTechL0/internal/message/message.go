@@ -0,0 +1,266 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/events"
+)
+
+const CurrentVersion = 2
+
+var (
+	ErrInvalidOrder       = errors.New("invalid order")
+	ErrMalformed          = errors.New("malformed order message")
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+)
+
+type Envelope struct {
+	SchemaVersion int             `json:"schema_version"`
+	ContentType   string          `json:"content_type,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+type Delivery struct {
+	Name    string `json:"name"`
+	Phone   string `json:"phone"`
+	Zip     string `json:"zip"`
+	City    string `json:"city"`
+	Address string `json:"address"`
+	Region  string `json:"region"`
+	Email   string `json:"email"`
+}
+
+type Payment struct {
+	Transaction  string `json:"transaction"`
+	RequestID    string `json:"request_id"`
+	Currency     string `json:"currency"`
+	Provider     string `json:"provider"`
+	Amount       int    `json:"amount"`
+	PaymentDt    int    `json:"payment_dt"`
+	Bank         string `json:"bank"`
+	DeliveryCost int    `json:"delivery_cost"`
+	GoodsTotal   int    `json:"goods_total"`
+	CustomFee    int    `json:"custom_fee"`
+}
+
+type Item struct {
+	ChrtID      int    `json:"chrt_id"`
+	TrackNumber string `json:"track_number"`
+	Price       int    `json:"price"`
+	RID         string `json:"rid"`
+	Name        string `json:"name"`
+	Sale        int    `json:"sale"`
+	Size        string `json:"size"`
+	TotalPrice  int    `json:"total_price"`
+	NMID        int    `json:"nm_id"`
+	Brand       string `json:"brand"`
+	Status      int    `json:"status"`
+}
+
+type Order struct {
+	OrderUID          string   `json:"order_uid"`
+	TrackNumber       string   `json:"track_number"`
+	Entry             string   `json:"entry"`
+	Delivery          Delivery `json:"delivery"`
+	Payment           Payment  `json:"payment"`
+	Items             []Item   `json:"items"`
+	Locale            string   `json:"locale"`
+	InternalSignature string   `json:"internal_signature"`
+	CustomerID        string   `json:"customer_id"`
+	DeliveryService   string   `json:"delivery_service"`
+	ShardKey          string   `json:"shardkey"`
+	SMID              int      `json:"sm_id"`
+	DateCreated       Date     `json:"date_created"`
+	OOFShard          string   `json:"oof_shard"`
+}
+
+func Decode(data []byte) (Order, error) {
+	envelope, err := unwrap(data)
+	if err != nil {
+		return Order{}, err
+	}
+	if envelope.SchemaVersion < 1 || envelope.SchemaVersion > CurrentVersion {
+		return Order{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, envelope.SchemaVersion)
+	}
+	if envelope.ContentType != "" {
+		format, err := ParseFormat(envelope.ContentType)
+		if err != nil {
+			return Order{}, err
+		}
+		if format != FormatJSON {
+			return unwrapBinary(format, envelope)
+		}
+	}
+
+	payload := envelope.Payload
+	for v := envelope.SchemaVersion; v < CurrentVersion; v++ {
+		payload, err = upcasters[v](payload)
+		if err != nil {
+			return Order{}, fmt.Errorf("upcasting schema version %d: %w", v, err)
+		}
+	}
+
+	var order Order
+	if err := decodeStrict(payload, &order); err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
+
+func unwrapBinary(format Format, envelope Envelope) (Order, error) {
+	if envelope.SchemaVersion != CurrentVersion {
+		return Order{}, fmt.Errorf("%w: %d for %s payloads", ErrUnsupportedVersion, envelope.SchemaVersion, format)
+	}
+	var payload []byte
+	if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+		return Order{}, fmt.Errorf("%w: %s payload must be a base64 string", ErrMalformed, format)
+	}
+	return DecodeAs(format, payload)
+}
+
+func Encode(order Order) ([]byte, error) {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{SchemaVersion: CurrentVersion, Payload: payload})
+}
+
+func unwrap(data []byte) (Envelope, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Envelope{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if _, ok := probe["schema_version"]; !ok {
+		return Envelope{SchemaVersion: 1, Payload: data}, nil
+	}
+
+	var envelope Envelope
+	if err := decodeStrict(data, &envelope); err != nil {
+		return Envelope{}, err
+	}
+	if len(envelope.Payload) == 0 {
+		return Envelope{}, fmt.Errorf("%w: payload is required", ErrMalformed)
+	}
+	return envelope, nil
+}
+
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("%w: unexpected data after the message", ErrMalformed)
+	}
+	return nil
+}
+
+func (o Order) Validate() error {
+	if o.OrderUID == "" {
+		return fmt.Errorf("%w: order_uid is required", ErrInvalidOrder)
+	}
+	if o.DateCreated == "" {
+		return fmt.Errorf("%w: date_created is required", ErrInvalidOrder)
+	}
+	if _, err := ParseDate(string(o.DateCreated)); err != nil {
+		return fmt.Errorf("%w: date_created: %v", ErrInvalidOrder, err)
+	}
+	return nil
+}
+
+func (o Order) CheckRequired() error {
+	if o.Payment.Transaction == "" {
+		return fmt.Errorf("%w: payment.transaction is required", ErrInvalidOrder)
+	}
+	for i, item := range o.Items {
+		if item.ChrtID == 0 {
+			return fmt.Errorf("%w: items[%d].chrt_id is required", ErrInvalidOrder, i)
+		}
+	}
+	return nil
+}
+
+func (o Order) Record() (db.OrderRecord, error) {
+	if err := o.Validate(); err != nil {
+		return db.OrderRecord{}, err
+	}
+	dateCreated, _ := ParseDate(string(o.DateCreated))
+
+	items := make([]db.Item, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = db.Item{
+			OrderUID:    o.OrderUID,
+			ChrtID:      item.ChrtID,
+			TrackNumber: item.TrackNumber,
+			Price:       item.Price,
+			RID:         item.RID,
+			Name:        item.Name,
+			Sale:        item.Sale,
+			Size:        item.Size,
+			TotalPrice:  item.TotalPrice,
+			NMID:        item.NMID,
+			Brand:       item.Brand,
+			Status:      item.Status,
+		}
+	}
+
+	return db.OrderRecord{
+		Order: db.Order{
+			OrderUID:          o.OrderUID,
+			TrackNumber:       o.TrackNumber,
+			Entry:             o.Entry,
+			Locale:            o.Locale,
+			InternalSignature: o.InternalSignature,
+			CustomerID:        o.CustomerID,
+			DeliveryService:   o.DeliveryService,
+			ShardKey:          o.ShardKey,
+			SMID:              o.SMID,
+			DateCreated:       dateCreated,
+			OOFShard:          o.OOFShard,
+		},
+		Delivery: db.Delivery{
+			OrderUID: o.OrderUID,
+			Name:     o.Delivery.Name,
+			Phone:    o.Delivery.Phone,
+			Zip:      o.Delivery.Zip,
+			City:     o.Delivery.City,
+			Address:  o.Delivery.Address,
+			Region:   o.Delivery.Region,
+			Email:    o.Delivery.Email,
+		},
+		Payment: db.Payment{
+			OrderUID:     o.OrderUID,
+			Transaction:  o.Payment.Transaction,
+			RequestID:    o.Payment.RequestID,
+			Currency:     strings.ToUpper(o.Payment.Currency),
+			Provider:     o.Payment.Provider,
+			Amount:       o.Payment.Amount,
+			PaymentDt:    o.Payment.PaymentDt,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: o.Payment.DeliveryCost,
+			GoodsTotal:   o.Payment.GoodsTotal,
+			CustomFee:    o.Payment.CustomFee,
+		},
+		Items: items,
+	}, nil
+}
+
+func (o Order) Event() (events.OrderEvent, error) {
+	record, err := o.Record()
+	if err != nil {
+		return events.OrderEvent{}, err
+	}
+	return events.OrderEvent{
+		Order:    record.Order,
+		Delivery: record.Delivery,
+		Payment:  record.Payment,
+		Items:    record.Items,
+	}, nil
+}
@@ -0,0 +1,259 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const legacyOrder = `{
+	"order_uid": "b563feb7b2b84b6test",
+	"track_number": "WBILMTESTTRACK",
+	"entry": "WBIL",
+	"delivery": {"name": "Test Testov", "phone": "+9720000000", "zip": "2639809", "city": "Kiryat Mozkin", "address": "Ploshad Mira 15", "region": "Kraiot", "email": "test@gmail.com"},
+	"payment": {"transaction": "b563feb7b2b84b6test", "request_id": "", "currency": "USD", "provider": "wbpay", "amount": 1817, "payment_dt": 1637907727, "bank": "alpha", "delivery_cost": 1500, "goods_total": 317, "custom_fee": 0},
+	"items": [{"chrt_id": 9934930, "track_number": "WBILMTESTTRACK", "price": 453, "rid": "ab4219087a764ae0btest", "name": "Mascaras", "sale": 30, "size": "0", "total_price": 317, "nm_id": 2389212, "brand": "Vivienne Sabo", "status": 202}],
+	"locale": "en",
+	"internal_signature": "",
+	"customer_id": "test",
+	"delivery_service": "meest",
+	"shardkey": "9",
+	"sm_id": 99,
+	"date_created": "2021-11-26T06:22:19Z",
+	"oof_shard": "1"
+}`
+
+func TestDecodeUpcastsLegacyMessages(t *testing.T) {
+	order, err := Decode([]byte(legacyOrder))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if order.ShardKey != "9" || order.Payment.Amount != 1817 || len(order.Items) != 1 {
+		t.Errorf("Expected legacy fields to be carried over, got %+v", order)
+	}
+
+	enveloped := `{"schema_version":1,"payload":` + legacyOrder + `}`
+	fromEnvelope, err := Decode([]byte(enveloped))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(fromEnvelope, order) {
+		t.Errorf("Expected %+v, got %+v", order, fromEnvelope)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	order, err := Decode([]byte(legacyOrder))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := Encode(order)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"schema_version":2,`) {
+		t.Errorf("Expected a version 2 envelope, got %s", data)
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(decoded, order) {
+		t.Errorf("Expected %+v, got %+v", order, decoded)
+	}
+}
+
+func TestDecodeIsStrict(t *testing.T) {
+	cases := map[string]error{
+		`{"order_uid":`:                                           ErrMalformed,
+		`{"order_uid":"x","colour":"red"}`:                        ErrMalformed,
+		`{"order_uid":"x","shard_key":"9"}`:                       ErrMalformed,
+		`{"schema_version":2,"payload":{"shard_key":"9"}}`:        ErrMalformed,
+		`{"schema_version":2,"payload":{},"extra":true}`:          ErrMalformed,
+		`{"schema_version":2}`:                                    ErrMalformed,
+		`{"schema_version":2,"payload":{"items":[{"colour":1}]}}`: ErrMalformed,
+		`{"schema_version":0,"payload":{}}`:                       ErrUnsupportedVersion,
+		`{"schema_version":3,"payload":{}}`:                       ErrUnsupportedVersion,
+	}
+	for data, expected := range cases {
+		if _, err := Decode([]byte(data)); !errors.Is(err, expected) {
+			t.Errorf("Message %s: expected %v, got %v", data, expected, err)
+		}
+	}
+}
+
+func TestRecordValidates(t *testing.T) {
+	order, err := Decode([]byte(legacyOrder))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	record, err := order.Record()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if record.Payment.OrderUID != order.OrderUID || record.Items[0].OrderUID != order.OrderUID {
+		t.Errorf("Expected order_uid on nested records, got %+v", record)
+	}
+
+	order.Items[0].ChrtID = 0
+	order.Payment.Transaction = ""
+	if _, err := order.Record(); err != nil {
+		t.Errorf("Expected broker orders without chrt_id or transaction to be accepted, got %v", err)
+	}
+	if err := order.CheckRequired(); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("Expected %v, got %v", ErrInvalidOrder, err)
+	}
+
+	order.OrderUID = ""
+	if _, err := order.Record(); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("Expected %v, got %v", ErrInvalidOrder, err)
+	}
+}
+
+func TestSchemasMatchStructs(t *testing.T) {
+	cases := map[int]reflect.Type{
+		1: reflect.TypeOf(orderV1{}),
+		2: reflect.TypeOf(Order{}),
+	}
+	for version, typ := range cases {
+		data, err := Schema(version)
+		if err != nil {
+			t.Fatalf("Expected schema version %d, got %v", version, err)
+		}
+		var schema struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Defs       map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"$defs"`
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatalf("Schema version %d: expected valid JSON, got %v", version, err)
+		}
+
+		compare(t, version, "order", schema.Properties, typ)
+		compare(t, version, "delivery", schema.Defs["delivery"].Properties, reflect.TypeOf(Delivery{}))
+		compare(t, version, "payment", schema.Defs["payment"].Properties, reflect.TypeOf(Payment{}))
+		compare(t, version, "item", schema.Defs["item"].Properties, reflect.TypeOf(Item{}))
+	}
+
+	if _, err := Schema(CurrentVersion + 1); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected %v, got %v", ErrUnsupportedVersion, err)
+	}
+}
+
+func compare(t *testing.T, version int, name string, properties map[string]json.RawMessage, typ reflect.Type) {
+	t.Helper()
+	var fromSchema, fromStruct []string
+	for key := range properties {
+		fromSchema = append(fromSchema, key)
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		fromStruct = append(fromStruct, typ.Field(i).Tag.Get("json"))
+	}
+	sort.Strings(fromSchema)
+	sort.Strings(fromStruct)
+	if !reflect.DeepEqual(fromSchema, fromStruct) {
+		t.Errorf("Schema version %d %s: expected properties %v, got %v", version, name, fromStruct, fromSchema)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	expected := time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2021-11-26T06:22:19Z":           expected,
+		"2021-11-26T09:22:19+03:00":      expected,
+		"2021-11-26T06:22:19.123456789Z": expected.Add(123456789),
+		"2021-11-26 06:22:19Z":           expected,
+		"2021-11-26T06:22:19":            expected,
+		"2021-11-26 06:22:19.5":          expected.Add(500 * time.Millisecond),
+		"2021-11-26":                     time.Date(2021, 11, 26, 0, 0, 0, 0, time.UTC),
+		"1637907739":                     expected,
+		"1637907739250":                  expected.Add(250 * time.Millisecond),
+	}
+	for value, want := range cases {
+		got, err := ParseDate(value)
+		if err != nil {
+			t.Errorf("Date %q: expected no error, got %v", value, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("Date %q: expected %v, got %v", value, want, got)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "26/11/2021", "-5"} {
+		if _, err := ParseDate(value); err == nil {
+			t.Errorf("Date %q: expected an error", value)
+		}
+	}
+}
+
+func TestDecodeNumericDate(t *testing.T) {
+	order, err := Decode([]byte(`{"schema_version":2,"payload":{"order_uid":"a","date_created":1637907739,"payment":{"transaction":"a","currency":"RUB"}}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	record, err := order.Record()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !record.Order.DateCreated.Equal(time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)) {
+		t.Errorf("Unexpected date_created: %v", record.Order.DateCreated)
+	}
+
+	data, err := msgpack.Marshal(map[string]any{"order_uid": "a", "date_created": 1637907739})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	order, err = DecodeAs(FormatMsgPack, data)
+	if err != nil || order.DateCreated != "1637907739" {
+		t.Errorf("Expected numeric msgpack date, got %q (%v)", order.DateCreated, err)
+	}
+}
+
+func TestPaymentConsistency(t *testing.T) {
+	cases := map[string]func(*Order){
+		"unknown currency": func(o *Order) { o.Payment.Currency = "XYZ" },
+		"missing currency": func(o *Order) { o.Payment.Currency = "" },
+		"amount mismatch":  func(o *Order) { o.Payment.Amount++ },
+		"goods mismatch":   func(o *Order) { o.Items[0].TotalPrice++ },
+		"negative fee":     func(o *Order) { o.Payment.CustomFee = -1; o.Payment.Amount-- },
+		"amount overflow":  func(o *Order) { o.Payment.Amount = math.MaxInt64 },
+	}
+	for name, mutate := range cases {
+		order, err := Decode([]byte(legacyOrder))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		mutate(&order)
+		if err := order.Validate(); err != nil {
+			t.Errorf("%s: expected payment checks to be separate from Validate, got %v", name, err)
+		}
+		if err := order.CheckPayment(); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("%s: expected %v, got %v", name, ErrInvalidOrder, err)
+		}
+	}
+
+	order, err := Decode([]byte(legacyOrder))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	order.Payment.Amount++
+	if err := order.CheckPayment(); err == nil || !strings.Contains(err.Error(), "1818.00 USD") || !strings.Contains(err.Error(), "1817.00 USD") {
+		t.Errorf("Expected amounts to be reported in major units, got %v", err)
+	}
+	order.Payment.Amount--
+
+	order.Payment.Currency = "usd"
+	record, err := order.Record()
+	if err != nil || record.Payment.Currency != "USD" {
+		t.Errorf("Expected normalized currency USD, got %q (%v)", record.Payment.Currency, err)
+	}
+}
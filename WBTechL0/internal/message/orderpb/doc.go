@@ -0,0 +1,3 @@
+package orderpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative order.proto
@@ -0,0 +1,56 @@
+package message
+
+import (
+	"fmt"
+
+	"WBTechL0/internal/money"
+)
+
+func (o Order) CheckPayment() error {
+	currency, err := money.LookupCurrency(o.Payment.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: payment.currency: %v", ErrInvalidOrder, err)
+	}
+
+	values := make(map[string]money.Money, 4)
+	for _, field := range []struct {
+		name  string
+		value int
+	}{{"amount", o.Payment.Amount}, {"goods_total", o.Payment.GoodsTotal}, {"delivery_cost", o.Payment.DeliveryCost}, {"custom_fee", o.Payment.CustomFee}} {
+		m, err := money.FromMajor(int64(field.value), currency)
+		if err != nil {
+			return fmt.Errorf("%w: payment.%s: %v", ErrInvalidOrder, field.name, err)
+		}
+		if m.IsNegative() {
+			return fmt.Errorf("%w: payment.%s must not be negative", ErrInvalidOrder, field.name)
+		}
+		values[field.name] = m
+	}
+	amount, goodsTotal, deliveryCost, customFee := values["amount"], values["goods_total"], values["delivery_cost"], values["custom_fee"]
+
+	expected, err := money.Sum(currency, goodsTotal, deliveryCost, customFee)
+	if err != nil {
+		return fmt.Errorf("%w: payment totals: %v", ErrInvalidOrder, err)
+	}
+	if !amount.Equal(expected) {
+		return fmt.Errorf("%w: payment.amount %s does not equal goods_total + delivery_cost + custom_fee = %s", ErrInvalidOrder, amount, expected)
+	}
+
+	if len(o.Items) == 0 {
+		return nil
+	}
+	itemTotals := make([]money.Money, len(o.Items))
+	for i, item := range o.Items {
+		if itemTotals[i], err = money.FromMajor(int64(item.TotalPrice), currency); err != nil {
+			return fmt.Errorf("%w: items[%d].total_price: %v", ErrInvalidOrder, i, err)
+		}
+	}
+	itemsTotal, err := money.Sum(currency, itemTotals...)
+	if err != nil {
+		return fmt.Errorf("%w: items total: %v", ErrInvalidOrder, err)
+	}
+	if !goodsTotal.Equal(itemsTotal) {
+		return fmt.Errorf("%w: payment.goods_total %s does not equal the items' total_price sum %s", ErrInvalidOrder, goodsTotal, itemsTotal)
+	}
+	return nil
+}
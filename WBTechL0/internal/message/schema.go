@@ -0,0 +1,17 @@
+package message
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+func Schema(version int) ([]byte, error) {
+	data, err := schemas.ReadFile(fmt.Sprintf("schemas/order.v%d.json", version))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	return data, nil
+}
@@ -0,0 +1,91 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var dateLayouts = []struct {
+	layout string
+	zoned  bool
+}{
+	{time.RFC3339Nano, true},
+	{"2006-01-02 15:04:05.999999999Z07:00", true},
+	{"2006-01-02T15:04:05.999999999", false},
+	{"2006-01-02 15:04:05.999999999", false},
+	{"2006-01-02", false},
+}
+
+const unixMillisThreshold = 1e11
+
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("empty date")
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if n < 0 {
+			return time.Time{}, fmt.Errorf("negative Unix timestamp %d", n)
+		}
+		if n >= unixMillisThreshold {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	for _, l := range dateLayouts {
+		var t time.Time
+		var err error
+		if l.zoned {
+			t, err = time.Parse(l.layout, value)
+		} else {
+			t, err = time.ParseInLocation(l.layout, value, time.UTC)
+		}
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q, expected RFC3339, a zone-less timestamp or Unix seconds/milliseconds", value)
+}
+
+type Date string
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*d = Date(n.String())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*d = Date(s)
+	return nil
+}
+
+func (d *Date) DecodeMsgpack(dec *msgpack.Decoder) error {
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case nil:
+		*d = ""
+	case string:
+		*d = Date(v)
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		*d = Date(fmt.Sprint(v))
+	default:
+		return fmt.Errorf("date_created must be a string or an integer, got %T", v)
+	}
+	return nil
+}
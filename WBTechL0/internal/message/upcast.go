@@ -0,0 +1,32 @@
+package message
+
+import "encoding/json"
+
+var upcasters = map[int]func(json.RawMessage) (json.RawMessage, error){
+	1: upcastV1,
+}
+
+type orderV1 struct {
+	OrderUID          string   `json:"order_uid"`
+	TrackNumber       string   `json:"track_number"`
+	Entry             string   `json:"entry"`
+	Delivery          Delivery `json:"delivery"`
+	Payment           Payment  `json:"payment"`
+	Items             []Item   `json:"items"`
+	Locale            string   `json:"locale"`
+	InternalSignature string   `json:"internal_signature"`
+	CustomerID        string   `json:"customer_id"`
+	DeliveryService   string   `json:"delivery_service"`
+	ShardKey          string   `json:"shardkey"`
+	SMID              int      `json:"sm_id"`
+	DateCreated       Date     `json:"date_created"`
+	OOFShard          string   `json:"oof_shard"`
+}
+
+func upcastV1(payload json.RawMessage) (json.RawMessage, error) {
+	var v1 orderV1
+	if err := decodeStrict(payload, &v1); err != nil {
+		return nil, err
+	}
+	return json.Marshal(Order(v1))
+}
@@ -0,0 +1,78 @@
+package money
+
+import "strings"
+
+type localeFormat struct {
+	decimal     string
+	group       string
+	symbolFirst bool
+	spaced      bool
+}
+
+const (
+	nbsp       = "\u00a0"
+	narrowNbsp = "\u202f"
+)
+
+var localeFormats = map[string]localeFormat{
+	"en": {decimal: ".", group: ",", symbolFirst: true},
+	"ja": {decimal: ".", group: ",", symbolFirst: true},
+	"zh": {decimal: ".", group: ",", symbolFirst: true},
+	"ko": {decimal: ".", group: ",", symbolFirst: true},
+	"tr": {decimal: ",", group: ".", symbolFirst: true},
+	"pt": {decimal: ",", group: ".", symbolFirst: true, spaced: true},
+	"de": {decimal: ",", group: ".", spaced: true},
+	"es": {decimal: ",", group: ".", spaced: true},
+	"it": {decimal: ",", group: ".", spaced: true},
+	"fr": {decimal: ",", group: narrowNbsp, spaced: true},
+	"ru": {decimal: ",", group: nbsp, spaced: true},
+	"uk": {decimal: ",", group: nbsp, spaced: true},
+	"be": {decimal: ",", group: nbsp, spaced: true},
+	"kk": {decimal: ",", group: nbsp, spaced: true},
+	"uz": {decimal: ",", group: nbsp, spaced: true},
+}
+
+func SupportedLocale(locale string) string {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := localeFormats[lang]; ok {
+		return lang
+	}
+	return "en"
+}
+
+func lookupLocale(locale string) localeFormat {
+	return localeFormats[SupportedLocale(locale)]
+}
+
+func (m Money) Format(locale string) string {
+	f := lookupLocale(locale)
+	sign, major, minor := m.parts()
+
+	var b strings.Builder
+	for i, d := range major {
+		if i > 0 && (len(major)-i)%3 == 0 {
+			b.WriteString(f.group)
+		}
+		b.WriteRune(d)
+	}
+	number := b.String()
+	if minor != "" {
+		number += f.decimal + minor
+	}
+
+	symbol, spaced := m.currency.Symbol, f.spaced
+	if symbol == "" {
+		symbol, spaced = m.currency.Code, true
+	}
+	sep := ""
+	if spaced {
+		sep = nbsp
+	}
+	if f.symbolFirst {
+		return sign + symbol + sep + number
+	}
+	return sign + number + sep + symbol
+}
@@ -0,0 +1,156 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount overflow")
+)
+
+type Money struct {
+	amount   int64
+	currency Currency
+}
+
+func New(amount int64, currency Currency) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+func Parse(amount int64, code string) (Money, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(amount, currency), nil
+}
+
+func FromMajor(amount int64, currency Currency) (Money, error) {
+	m := New(amount, currency)
+	for i := 0; i < currency.Exponent; i++ {
+		var err error
+		if m, err = m.Mul(10); err != nil {
+			return Money{}, err
+		}
+	}
+	return m, nil
+}
+
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	sum := m.amount + other.amount
+	if (other.amount > 0 && sum < m.amount) || (other.amount < 0 && sum > m.amount) {
+		return Money{}, ErrOverflow
+	}
+	return New(sum, m.currency), nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(New(-other.amount, other.currency))
+}
+
+func (m Money) Mul(n int64) (Money, error) {
+	if m.amount == 0 || n == 0 {
+		return New(0, m.currency), nil
+	}
+	product := m.amount * n
+	if product/n != m.amount || (m.amount == -1 && n == math.MinInt64) || (n == -1 && m.amount == math.MinInt64) {
+		return Money{}, ErrOverflow
+	}
+	return New(product, m.currency), nil
+}
+
+func Sum(currency Currency, values ...Money) (Money, error) {
+	total := New(0, currency)
+	for _, v := range values {
+		var err error
+		if total, err = total.Add(v); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.amount < other.amount:
+		return -1, nil
+	case m.amount > other.amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.currency.Code == other.currency.Code && m.amount == other.amount
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.currency.Code != other.currency.Code {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency.Code, other.currency.Code)
+	}
+	return nil
+}
+
+func (m Money) Decimal() string {
+	sign, major, minor := m.parts()
+	if minor == "" {
+		return sign + major
+	}
+	return sign + major + "." + minor
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency.Code
+}
+
+func (m Money) parts() (sign, major, minor string) {
+	digits := strconv.FormatUint(absUint(m.amount), 10)
+	if m.amount < 0 {
+		sign = "-"
+	}
+	exp := m.currency.Exponent
+	if exp == 0 {
+		return sign, digits, ""
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign, digits[:len(digits)-exp], digits[len(digits)-exp:]
+}
+
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
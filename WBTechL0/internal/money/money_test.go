@@ -0,0 +1,146 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	cases := map[string]int{"USD": 2, "rub": 2, " JPY ": 0, "KWD": 3, "CLF": 4}
+	for code, exponent := range cases {
+		c, err := LookupCurrency(code)
+		if err != nil || c.Exponent != exponent {
+			t.Errorf("Currency %q: expected exponent %d, got %+v (%v)", code, exponent, c, err)
+		}
+	}
+
+	for _, code := range []string{"", "US", "XXX", "DOLLAR"} {
+		if _, err := LookupCurrency(code); !errors.Is(err, ErrUnknownCurrency) {
+			t.Errorf("Currency %q: expected %v, got %v", code, ErrUnknownCurrency, err)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	cases := []struct {
+		amount   int64
+		code     string
+		expected string
+	}{
+		{1817, "USD", "18.17"},
+		{5, "USD", "0.05"},
+		{-5, "USD", "-0.05"},
+		{1817, "JPY", "1817"},
+		{1817, "KWD", "1.817"},
+		{0, "EUR", "0.00"},
+		{math.MinInt64, "USD", "-92233720368547758.08"},
+	}
+	for _, tc := range cases {
+		m, err := Parse(tc.amount, tc.code)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := m.Decimal(); got != tc.expected {
+			t.Errorf("%d %s: expected %s, got %s", tc.amount, tc.code, tc.expected, got)
+		}
+	}
+}
+
+func TestFromMajor(t *testing.T) {
+	cases := map[string]string{"USD": "1817.00", "JPY": "1817", "KWD": "1817.000"}
+	for code, expected := range cases {
+		currency, _ := LookupCurrency(code)
+		m, err := FromMajor(1817, currency)
+		if err != nil || m.Decimal() != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", code, expected, m.Decimal(), err)
+		}
+	}
+
+	usd, _ := LookupCurrency("USD")
+	if _, err := FromMajor(math.MaxInt64, usd); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected %v, got %v", ErrOverflow, err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		amount   int64
+		code     string
+		locale   string
+		expected string
+	}{
+		{181700, "USD", "en", "$1,817.00"},
+		{181700, "USD", "en-US", "$1,817.00"},
+		{-181700, "USD", "en", "-$1,817.00"},
+		{123456789, "RUB", "ru", "1\u00a0234\u00a0567,89\u00a0₽"},
+		{123456789, "EUR", "de_DE", "1.234.567,89\u00a0€"},
+		{123456789, "EUR", "fr", "1\u202f234\u202f567,89\u00a0€"},
+		{1817, "JPY", "ja", "¥1,817"},
+		{1817, "CHF", "en", "CHF\u00a018.17"},
+		{1817, "CHF", "de", "18,17\u00a0CHF"},
+		{1817, "USD", "xx", "$18.17"},
+	}
+	for _, tc := range cases {
+		m, err := Parse(tc.amount, tc.code)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := m.Format(tc.locale); got != tc.expected {
+			t.Errorf("%d %s in %s: expected %q, got %q", tc.amount, tc.code, tc.locale, tc.expected, got)
+		}
+	}
+}
+
+func TestSupportedLocale(t *testing.T) {
+	cases := map[string]string{"ru": "ru", "ru-RU": "ru", "de_DE": "de", "EN": "en", "xx": "en", "aaaaaaaa": "en"}
+	for locale, expected := range cases {
+		if got := SupportedLocale(locale); got != expected {
+			t.Errorf("Locale %q: expected %s, got %s", locale, expected, got)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	usd, _ := LookupCurrency("USD")
+	rub, _ := LookupCurrency("RUB")
+
+	total, err := Sum(usd, New(317, usd), New(1500, usd), New(0, usd))
+	if err != nil || !total.Equal(New(1817, usd)) {
+		t.Errorf("Expected 18.17 USD, got %v (%v)", total, err)
+	}
+
+	diff, err := total.Sub(New(1817, usd))
+	if err != nil || !diff.IsZero() {
+		t.Errorf("Expected zero, got %v (%v)", diff, err)
+	}
+
+	tripled, err := New(317, usd).Mul(3)
+	if err != nil || tripled.Amount() != 951 {
+		t.Errorf("Expected 951, got %v (%v)", tripled, err)
+	}
+
+	if c, err := New(1, usd).Cmp(New(2, usd)); err != nil || c != -1 {
+		t.Errorf("Expected -1, got %d (%v)", c, err)
+	}
+
+	if _, err := New(1, usd).Add(New(1, rub)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected %v, got %v", ErrCurrencyMismatch, err)
+	}
+	if _, err := New(1, usd).Cmp(New(1, rub)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected %v, got %v", ErrCurrencyMismatch, err)
+	}
+	if New(1, usd).Equal(New(1, rub)) {
+		t.Error("Expected amounts in different currencies not to be equal")
+	}
+
+	if _, err := New(math.MaxInt64, usd).Add(New(1, usd)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected %v, got %v", ErrOverflow, err)
+	}
+	if _, err := New(math.MinInt64, usd).Sub(New(1, usd)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected %v, got %v", ErrOverflow, err)
+	}
+	if _, err := New(math.MaxInt64/2+1, usd).Mul(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected %v, got %v", ErrOverflow, err)
+	}
+}
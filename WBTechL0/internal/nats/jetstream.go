@@ -0,0 +1,196 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"WBTechL0/internal/backoff"
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ingest"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const jetStreamSetupTimeout = 10 * time.Second
+
+type JetStreamSource struct {
+	connState
+	cfg       config.NATSConfig
+	consuming atomic.Bool
+	mu        sync.Mutex
+	conn      *nats.Conn
+}
+
+func NewJetStreamSource(cfg config.NATSConfig) *JetStreamSource {
+	return &JetStreamSource{
+		connState: connState{name: "JetStream"},
+		cfg:       cfg,
+	}
+}
+
+func (s *JetStreamSource) Start(ctx context.Context, handle func(ingest.Delivery)) error {
+	s.setState(StateConnecting)
+
+	nc, err := nats.Connect(s.cfg.URL,
+		nats.Name(s.cfg.ClientID),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.CustomReconnectDelay(func(attempts int) time.Duration {
+			return backoff.Delay(attempts-1, s.cfg.ReconnectBaseDelay, s.cfg.ReconnectMaxDelay)
+		}),
+		nats.PingInterval(time.Duration(s.cfg.PingInterval)*time.Second),
+		nats.MaxPingsOutstanding(s.cfg.PingMaxOut),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if s.consuming.Load() {
+				log.Println("JetStream connection lost:", err)
+				s.setState(StateDisconnected)
+			}
+		}),
+		nats.ReconnectHandler(func(*nats.Conn) {
+			if s.consuming.Load() {
+				s.connected()
+			}
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.conn = nc
+	s.mu.Unlock()
+
+	lost := make(chan error, 1)
+	attempt := 0
+	for {
+		cc, err := s.consume(ctx, nc, handle, lost)
+		if err == nil {
+			attempt = 0
+			s.consuming.Store(true)
+			if nc.IsConnected() {
+				s.connected()
+			}
+
+			select {
+			case err = <-lost:
+				cc.Stop()
+				s.consuming.Store(false)
+			case <-ctx.Done():
+				cc.Drain()
+				<-cc.Closed()
+				return nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		s.setState(StateDisconnected)
+
+		delay := backoff.Delay(attempt, s.cfg.ReconnectBaseDelay, s.cfg.ReconnectMaxDelay)
+		attempt++
+		log.Printf("JetStream consumer unavailable, re-creating it in %s: %v\n", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *JetStreamSource) consume(ctx context.Context, nc *nats.Conn, handle func(ingest.Delivery), lost chan<- error) (jetstream.ConsumeContext, error) {
+	ctx, cancel := context.WithTimeout(ctx, jetStreamSetupTimeout)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := js.Stream(ctx, s.cfg.Stream)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		stream, err = js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:     s.cfg.Stream,
+			Subjects: []string{s.cfg.Subject},
+			Storage:  jetstream.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up stream %s: %w", s.cfg.Stream, err)
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       s.cfg.DurableName,
+		FilterSubject: s.cfg.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       s.cfg.AckWait,
+		MaxAckPending: s.cfg.MaxInflight,
+		MaxDeliver:    s.cfg.MaxDeliver,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating consumer %s: %w", s.cfg.DurableName, err)
+	}
+
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		handle(jetStreamDelivery(msg))
+	},
+		jetstream.PullMaxMessages(s.cfg.MaxInflight),
+		jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+			if errors.Is(err, jetstream.ErrConsumerDeleted) || errors.Is(err, jetstream.ErrConsumerNotFound) {
+				select {
+				case lost <- err:
+				default:
+				}
+				return
+			}
+			log.Println("JetStream consume error:", err)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Consuming JetStream stream %s with durable consumer %s\n", s.cfg.Stream, s.cfg.DurableName)
+	return cc, nil
+}
+
+func jetStreamDelivery(msg jetstream.Msg) ingest.Delivery {
+	d := ingest.Delivery{ID: msg.Subject(), Data: msg.Data(), Ref: msg}
+	if meta, err := msg.Metadata(); err == nil {
+		d.ID = fmt.Sprintf("%s/%d", meta.Stream, meta.Sequence.Stream)
+	}
+	if len(msg.Headers()) > 0 {
+		d.Headers = make(map[string]string, len(msg.Headers()))
+		for key := range msg.Headers() {
+			d.Headers[key] = msg.Headers().Get(key)
+		}
+	}
+	return d
+}
+
+func (s *JetStreamSource) Ack(d ingest.Delivery) error {
+	return d.Ref.(jetstream.Msg).Ack()
+}
+
+func (s *JetStreamSource) Nack(d ingest.Delivery, retry bool) error {
+	msg := d.Ref.(jetstream.Msg)
+	if retry {
+		return msg.NakWithDelay(s.cfg.NakDelay)
+	}
+	return msg.Term()
+}
+
+func (s *JetStreamSource) Close() error {
+	s.setState(StateClosed)
+	s.mu.Lock()
+	nc := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+	if nc != nil {
+		nc.Close()
+	}
+	return nil
+}
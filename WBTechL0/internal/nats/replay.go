@@ -0,0 +1,141 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ingest"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/stan.go"
+)
+
+type ReplayStart struct {
+	Sequence uint64
+	Time     time.Time
+}
+
+func (r ReplayStart) Validate() error {
+	if (r.Sequence == 0) == r.Time.IsZero() {
+		return errors.New("exactly one of a start sequence or a start time is required")
+	}
+	return nil
+}
+
+func NewReplaySource(cfg config.NATSConfig, start ReplayStart) (ingest.Source, error) {
+	if err := start.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Backend {
+	case BackendSTAN, "":
+		return &stanReplaySource{cfg: cfg, start: start}, nil
+	case BackendJetStream:
+		return &jetStreamReplaySource{cfg: cfg, start: start}, nil
+	}
+	return nil, fmt.Errorf("unknown NATS backend %q", cfg.Backend)
+}
+
+type stanReplaySource struct {
+	cfg   config.NATSConfig
+	start ReplayStart
+}
+
+func (s *stanReplaySource) Start(ctx context.Context, handle func(ingest.Delivery)) error {
+	clientID := fmt.Sprintf("%s-replay-%d", s.cfg.ClientID, time.Now().UnixNano())
+	sc, err := stan.Connect(s.cfg.ClusterID, clientID, stan.NatsURL(s.cfg.URL))
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+
+	startAt := stan.StartAtSequence(s.start.Sequence)
+	if !s.start.Time.IsZero() {
+		startAt = stan.StartAtTime(s.start.Time)
+	}
+	sub, err := sc.Subscribe(s.cfg.Subject, func(msg *stan.Msg) {
+		handle(ingest.Delivery{
+			ID:   strconv.FormatUint(msg.Sequence, 10),
+			Data: msg.Data,
+			Ref:  msg,
+		})
+	},
+		startAt,
+		stan.SetManualAckMode(),
+		stan.AckWait(s.cfg.AckWait),
+		stan.MaxInflight(s.cfg.MaxInflight),
+	)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	<-ctx.Done()
+	return nil
+}
+
+func (s *stanReplaySource) Ack(d ingest.Delivery) error {
+	return d.Ref.(*stan.Msg).Ack()
+}
+
+func (s *stanReplaySource) Nack(d ingest.Delivery, retry bool) error {
+	return s.Ack(d)
+}
+
+type jetStreamReplaySource struct {
+	cfg   config.NATSConfig
+	start ReplayStart
+}
+
+func (s *jetStreamReplaySource) Start(ctx context.Context, handle func(ingest.Delivery)) error {
+	nc, err := nats.Connect(s.cfg.URL, nats.Name(s.cfg.ClientID+"-replay"))
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return err
+	}
+
+	consumerCfg := jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{s.cfg.Subject},
+		DeliverPolicy:  jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:    s.start.Sequence,
+	}
+	if !s.start.Time.IsZero() {
+		consumerCfg.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		consumerCfg.OptStartSeq = 0
+		consumerCfg.OptStartTime = &s.start.Time
+	}
+
+	setupCtx, cancel := context.WithTimeout(ctx, jetStreamSetupTimeout)
+	consumer, err := js.OrderedConsumer(setupCtx, s.cfg.Stream, consumerCfg)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("creating replay consumer on %s: %w", s.cfg.Stream, err)
+	}
+
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		handle(jetStreamDelivery(msg))
+	})
+	if err != nil {
+		return err
+	}
+	defer cc.Stop()
+
+	<-ctx.Done()
+	return nil
+}
+
+func (s *jetStreamReplaySource) Ack(d ingest.Delivery) error {
+	return nil
+}
+
+func (s *jetStreamReplaySource) Nack(d ingest.Delivery, retry bool) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ingest"
+)
+
+type State int32
+
+const (
+	StateConnecting State = iota
+	StateConnected
+	StateDisconnected
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+var ErrNotConnected = errors.New("not connected to NATS")
+
+const (
+	BackendSTAN      = "stan"
+	BackendJetStream = "jetstream"
+)
+
+func NewSource(cfg config.NATSConfig) (ingest.Source, error) {
+	switch cfg.Backend {
+	case BackendSTAN, "":
+		return NewSTANSource(cfg), nil
+	case BackendJetStream:
+		return NewJetStreamSource(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown NATS backend %q", cfg.Backend)
+}
+
+type connState struct {
+	name     string
+	state    atomic.Int32
+	connects atomic.Uint64
+}
+
+func (c *connState) State() State {
+	return State(c.state.Load())
+}
+
+func (c *connState) Reconnects() uint64 {
+	if n := c.connects.Load(); n > 1 {
+		return n - 1
+	}
+	return 0
+}
+
+func (c *connState) Ready(ctx context.Context) error {
+	if c.State() != StateConnected {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+func (c *connState) setState(state State) {
+	if State(c.state.Swap(int32(state))) != state {
+		log.Printf("%s subscriber state: %s\n", c.name, state)
+	}
+}
+
+func (c *connState) connected() {
+	c.connects.Add(1)
+	c.setState(StateConnected)
+}
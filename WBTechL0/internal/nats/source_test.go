@@ -0,0 +1,82 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ingest"
+)
+
+func TestSourcesStartUntilCanceled(t *testing.T) {
+	for _, backend := range []string{BackendSTAN, BackendJetStream} {
+		t.Run(backend, func(t *testing.T) {
+			testStartUntilCanceled(t, backend)
+		})
+	}
+}
+
+func testStartUntilCanceled(t *testing.T, backend string) {
+	source, err := NewSource(config.NATSConfig{
+		Backend:            backend,
+		URL:                "nats://127.0.0.1:1",
+		ClusterID:          "test-cluster",
+		ClientID:           "test-client",
+		ReconnectBaseDelay: 10 * time.Millisecond,
+		ReconnectMaxDelay:  20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor := source.(interface {
+		ingest.Monitor
+		State() State
+		Close() error
+	})
+
+	if err := monitor.Ready(context.Background()); err == nil {
+		t.Error("Expected source not to be ready before connecting")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- source.Start(ctx, func(ingest.Delivery) {}) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Start to return nil after cancellation, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start did not return after context cancellation")
+	}
+	monitor.Close()
+	if monitor.State() != StateClosed {
+		t.Errorf("Expected state %v, got %v", StateClosed, monitor.State())
+	}
+	if monitor.Reconnects() != 0 {
+		t.Errorf("Expected no reconnects, got %d", monitor.Reconnects())
+	}
+}
+
+func TestNewSourceRejectsUnknown(t *testing.T) {
+	if _, err := NewSource(config.NATSConfig{Backend: "kafka"}); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestReplayStartValidate(t *testing.T) {
+	cases := map[ReplayStart]bool{
+		{}:                               false,
+		{Sequence: 10}:                   true,
+		{Time: time.Now()}:               true,
+		{Sequence: 10, Time: time.Now()}: false,
+	}
+	for start, valid := range cases {
+		if err := start.Validate(); (err == nil) != valid {
+			t.Errorf("ReplayStart %+v: expected valid=%t, got %v", start, valid, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package nats
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"WBTechL0/internal/backoff"
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ingest"
+	"github.com/nats-io/stan.go"
+)
+
+type STANSource struct {
+	connState
+	cfg  config.NATSConfig
+	mu   sync.Mutex
+	conn stan.Conn
+}
+
+func NewSTANSource(cfg config.NATSConfig) *STANSource {
+	return &STANSource{
+		connState: connState{name: "NATS Streaming"},
+		cfg:       cfg,
+	}
+}
+
+func (s *STANSource) Start(ctx context.Context, handle func(ingest.Delivery)) error {
+	attempt := 0
+	for {
+		connected, err := s.session(ctx, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			attempt = 0
+		}
+		s.setState(StateDisconnected)
+
+		delay := backoff.Delay(attempt, s.cfg.ReconnectBaseDelay, s.cfg.ReconnectMaxDelay)
+		attempt++
+		log.Printf("NATS Streaming connection unavailable, reconnecting in %s: %v\n", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *STANSource) session(ctx context.Context, handle func(ingest.Delivery)) (bool, error) {
+	s.setState(StateConnecting)
+
+	lost := make(chan error, 1)
+	sc, err := stan.Connect(s.cfg.ClusterID, s.cfg.ClientID,
+		stan.NatsURL(s.cfg.URL),
+		stan.Pings(s.cfg.PingInterval, s.cfg.PingMaxOut),
+		stan.SetConnectionLostHandler(func(_ stan.Conn, reason error) {
+			select {
+			case lost <- reason:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	sub, err := sc.Subscribe(s.cfg.Subject, func(msg *stan.Msg) {
+		handle(ingest.Delivery{
+			ID:   strconv.FormatUint(msg.Sequence, 10),
+			Data: msg.Data,
+			Ref:  msg,
+		})
+	},
+		stan.DurableName(s.cfg.DurableName),
+		stan.SetManualAckMode(),
+		stan.AckWait(s.cfg.AckWait),
+		stan.MaxInflight(s.cfg.MaxInflight),
+	)
+	if err != nil {
+		sc.Close()
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.conn = sc
+	s.mu.Unlock()
+	log.Println("Subscribed to NATS subject:", s.cfg.Subject)
+	s.connected()
+
+	select {
+	case err := <-lost:
+		s.closeConn()
+		return true, err
+	case <-ctx.Done():
+		sub.Close()
+		return true, ctx.Err()
+	}
+}
+
+func (s *STANSource) Ack(d ingest.Delivery) error {
+	return d.Ref.(*stan.Msg).Ack()
+}
+
+func (s *STANSource) Nack(d ingest.Delivery, retry bool) error {
+	if retry {
+		return nil
+	}
+	return s.Ack(d)
+}
+
+func (s *STANSource) Close() error {
+	s.setState(StateClosed)
+	return s.closeConn()
+}
+
+func (s *STANSource) closeConn() error {
+	s.mu.Lock()
+	sc := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+	if sc == nil {
+		return nil
+	}
+	return sc.Close()
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrInvalidQuota = errors.New("quota must allow at least one request per positive window")
+
+type Quota struct {
+	Requests int
+	Window   time.Duration
+}
+
+type window struct {
+	start time.Time
+	used  int
+}
+
+type QuotaTracker struct {
+	mu        sync.Mutex
+	quota     Quota
+	windows   map[string]*window
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewQuota(quota Quota) (*QuotaTracker, error) {
+	if quota.Requests < 1 || quota.Window <= 0 {
+		return nil, ErrInvalidQuota
+	}
+	return &QuotaTracker{
+		quota:   quota,
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}, nil
+}
+
+func (q *QuotaTracker) Quota() Quota {
+	return q.quota
+}
+
+func (q *QuotaTracker) Allow(key string) Result {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.sweep(now)
+
+	w, ok := q.windows[key]
+	if !ok || now.Sub(w.start) >= q.quota.Window {
+		w = &window{start: now}
+		q.windows[key] = w
+	}
+
+	reset := w.start.Add(q.quota.Window).Sub(now)
+	result := Result{Limit: q.quota.Requests, Reset: reset}
+	if w.used < q.quota.Requests {
+		w.used++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = reset
+	}
+	result.Remaining = q.quota.Requests - w.used
+	return result
+}
+
+func (q *QuotaTracker) sweep(now time.Time) {
+	if now.Sub(q.lastSweep) < sweepInterval {
+		return
+	}
+	q.lastSweep = now
+	for key, w := range q.windows {
+		if now.Sub(w.start) >= q.quota.Window {
+			delete(q.windows, key)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+var ErrInvalidLimit = errors.New("rate limit must be positive")
+
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type Limiter struct {
+	mu        sync.Mutex
+	limit     Limit
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func New(limit Limit) (*Limiter, error) {
+	if !(limit.Rate > 0) || math.IsInf(limit.Rate, 1) {
+		return nil, ErrInvalidLimit
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &Limiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}, nil
+}
+
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	burst := float64(l.limit.Burst)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*l.limit.Rate)
+		b.last = now
+	}
+
+	result := Result{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.durationFor(1 - b.tokens)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = l.durationFor(burst - b.tokens)
+	return result
+}
+
+func (l *Limiter) durationFor(tokens float64) time.Duration {
+	return time.Duration(tokens / l.limit.Rate * float64(time.Second))
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	refill := l.durationFor(float64(l.limit.Burst))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > refill {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter, err := New(Limit{Rate: 2, Burst: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if result := limiter.Allow("client"); !result.Allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+
+	result := limiter.Allow("client")
+	if result.Allowed {
+		t.Fatal("Expected request over burst to be rejected")
+	}
+	if result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected retry after 500ms, got %s", result.RetryAfter)
+	}
+	if result.Remaining != 0 || result.Limit != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if !limiter.Allow("other").Allowed {
+		t.Error("Expected a different key to have its own bucket")
+	}
+
+	now = now.Add(time.Second)
+	result = limiter.Allow("client")
+	if !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Expected refilled bucket to allow request with 1 remaining, got %+v", result)
+	}
+
+	now = now.Add(2 * sweepInterval)
+	limiter.Allow("client")
+	if _, ok := limiter.buckets["other"]; ok {
+		t.Error("Expected idle bucket to be swept")
+	}
+}
+
+func TestNewRejectsNonPositiveRate(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		if _, err := New(Limit{Rate: rate, Burst: 1}); err != ErrInvalidLimit {
+			t.Errorf("Expected ErrInvalidLimit for rate %v, got %v", rate, err)
+		}
+	}
+}
+
+func TestQuotaTracker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	quota, err := NewQuota(Quota{Requests: 2, Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quota.now = func() time.Time { return now }
+
+	quota.Allow("client")
+	now = now.Add(10 * time.Minute)
+	if result := quota.Allow("client"); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Expected second request to be allowed with 0 remaining, got %+v", result)
+	}
+	result := quota.Allow("client")
+	if result.Allowed || result.RetryAfter != 50*time.Minute {
+		t.Errorf("Expected request over quota to be rejected until the window resets, got %+v", result)
+	}
+
+	now = now.Add(50 * time.Minute)
+	if result := quota.Allow("client"); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Expected a new window to allow requests, got %+v", result)
+	}
+
+	if _, err := NewQuota(Quota{Requests: 0, Window: time.Hour}); err != ErrInvalidQuota {
+		t.Errorf("Expected ErrInvalidQuota, got %v", err)
+	}
+}
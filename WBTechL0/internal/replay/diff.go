@@ -0,0 +1,62 @@
+package replay
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"WBTechL0/internal/db"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func Diff(before, after db.OrderRecord) []string {
+	var changes []string
+	changes = diffStruct(changes, "", reflect.ValueOf(before.Order), reflect.ValueOf(after.Order))
+	changes = diffStruct(changes, "delivery.", reflect.ValueOf(before.Delivery), reflect.ValueOf(after.Delivery))
+	changes = diffStruct(changes, "payment.", reflect.ValueOf(before.Payment), reflect.ValueOf(after.Payment))
+
+	beforeItems, afterItems := sortedItems(before.Items), sortedItems(after.Items)
+	if len(beforeItems) != len(afterItems) {
+		return append(changes, "items")
+	}
+	for i := range beforeItems {
+		changes = diffStruct(changes, fmt.Sprintf("items[%d].", i), reflect.ValueOf(beforeItems[i]), reflect.ValueOf(afterItems[i]))
+	}
+	return changes
+}
+
+func diffStruct(changes []string, prefix string, before, after reflect.Value) []string {
+	t := before.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || name == "order_uid" {
+			continue
+		}
+		a, b := before.Field(i), after.Field(i)
+		if field.Type == timeType {
+			if !a.Interface().(time.Time).Equal(b.Interface().(time.Time)) {
+				changes = append(changes, prefix+name)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			changes = append(changes, prefix+name)
+		}
+	}
+	return changes
+}
+
+func sortedItems(items []db.Item) []db.Item {
+	sorted := append([]db.Item(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].ChrtID != sorted[j].ChrtID {
+			return sorted[i].ChrtID < sorted[j].ChrtID
+		}
+		return sorted[i].RID < sorted[j].RID
+	})
+	return sorted
+}
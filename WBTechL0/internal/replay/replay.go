@@ -0,0 +1,219 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/ingest"
+)
+
+type Outcome string
+
+const (
+	OutcomeInsert    Outcome = "insert"
+	OutcomeUnchanged Outcome = "unchanged"
+	OutcomeChanged   Outcome = "changed"
+	OutcomeRejected  Outcome = "rejected"
+	OutcomeFailed    Outcome = "failed"
+)
+
+const DefaultBatchSize = 100
+
+type Entry struct {
+	Message  string   `json:"message"`
+	OrderUID string   `json:"order_uid,omitempty"`
+	Outcome  Outcome  `json:"outcome"`
+	Applied  bool     `json:"applied,omitempty"`
+	Changes  []string `json:"changes,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type Report struct {
+	Messages  int
+	Insert    int
+	Unchanged int
+	Changed   int
+	Rejected  int
+	Failed    int
+	Applied   int
+}
+
+type LookupFunc func(ctx context.Context, orderUIDs []string) ([]db.OrderRecord, error)
+
+type Options struct {
+	Apply     bool
+	BatchSize int
+	Entries   io.Writer
+}
+
+type pendingEntry struct {
+	id     string
+	record db.OrderRecord
+	err    error
+}
+
+type Replayer struct {
+	decode  ingest.DecodeFunc
+	lookup  LookupFunc
+	store   ingest.StoreFunc
+	opts    Options
+	entries *json.Encoder
+
+	mu      sync.Mutex
+	pending []pendingEntry
+	known   map[string]db.OrderRecord
+	report  Report
+	closed  bool
+}
+
+func New(decode ingest.DecodeFunc, lookup LookupFunc, store ingest.StoreFunc, opts Options) *Replayer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	r := &Replayer{
+		decode: decode,
+		lookup: lookup,
+		store:  store,
+		opts:   opts,
+		known:  make(map[string]db.OrderRecord),
+	}
+	if opts.Entries != nil {
+		r.entries = json.NewEncoder(opts.Entries)
+	}
+	return r
+}
+
+func (r *Replayer) Handle(ctx context.Context, d ingest.Delivery) error {
+	record, err := r.decode(d)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.pending = append(r.pending, pendingEntry{id: d.ID, record: record, err: err})
+	if len(r.pending) < r.opts.BatchSize {
+		return nil
+	}
+	return r.flush(ctx)
+}
+
+func (r *Replayer) Finish(ctx context.Context) (Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	err := r.flush(ctx)
+	return r.report, err
+}
+
+func (r *Replayer) flush(ctx context.Context) error {
+	batch := r.pending
+	r.pending = nil
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var lookup []string
+	for _, p := range batch {
+		if p.err != nil {
+			continue
+		}
+		if _, ok := r.known[p.record.Order.OrderUID]; !ok {
+			lookup = append(lookup, p.record.Order.OrderUID)
+		}
+	}
+	existing := make(map[string]db.OrderRecord)
+	if len(lookup) > 0 {
+		records, err := r.lookup(ctx, lookup)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			existing[record.Order.OrderUID] = record
+		}
+	}
+
+	entries := make([]Entry, len(batch))
+	var inserts []db.OrderRecord
+	var insertIdx []int
+	for i, p := range batch {
+		entry := Entry{Message: p.id}
+		switch {
+		case p.err != nil:
+			entry.Outcome = OutcomeRejected
+			entry.Error = p.err.Error()
+		default:
+			uid := p.record.Order.OrderUID
+			entry.OrderUID = uid
+			base, ok := r.known[uid]
+			if !ok {
+				base, ok = existing[uid]
+			}
+			if ok {
+				entry.Changes = Diff(base, p.record)
+				entry.Outcome = OutcomeUnchanged
+				if len(entry.Changes) > 0 {
+					entry.Outcome = OutcomeChanged
+				}
+				r.known[uid] = base
+			} else {
+				entry.Outcome = OutcomeInsert
+				r.known[uid] = p.record
+				inserts = append(inserts, p.record)
+				insertIdx = append(insertIdx, i)
+			}
+		}
+		entries[i] = entry
+	}
+
+	if r.opts.Apply && len(inserts) > 0 {
+		errs, err := r.store(ctx, inserts)
+		if err != nil {
+			for _, record := range inserts {
+				delete(r.known, record.Order.OrderUID)
+			}
+			return err
+		}
+		for j, i := range insertIdx {
+			if errs != nil && errs[j] != nil {
+				entries[i].Outcome = OutcomeFailed
+				entries[i].Error = errs[j].Error()
+				delete(r.known, inserts[j].Order.OrderUID)
+				continue
+			}
+			entries[i].Applied = true
+		}
+	}
+
+	for _, entry := range entries {
+		r.count(entry)
+		if r.entries != nil {
+			if err := r.entries.Encode(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Replayer) count(entry Entry) {
+	r.report.Messages++
+	switch entry.Outcome {
+	case OutcomeInsert:
+		r.report.Insert++
+	case OutcomeUnchanged:
+		r.report.Unchanged++
+	case OutcomeChanged:
+		r.report.Changed++
+	case OutcomeRejected:
+		r.report.Rejected++
+	case OutcomeFailed:
+		r.report.Failed++
+	}
+	if entry.Applied {
+		r.report.Applied++
+	}
+}
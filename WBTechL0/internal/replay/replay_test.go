@@ -0,0 +1,141 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/ingest"
+)
+
+func record(uid string, amount int) db.OrderRecord {
+	return db.OrderRecord{
+		Order:   db.Order{OrderUID: uid, TrackNumber: "TRACK", DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)},
+		Payment: db.Payment{OrderUID: uid, Transaction: uid, Amount: amount},
+		Items: []db.Item{
+			{OrderUID: uid, ChrtID: 2, Price: 10},
+			{OrderUID: uid, ChrtID: 1, Price: 20},
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := record("a", 100)
+	after := record("a", 150)
+	after.Order.DateCreated = before.Order.DateCreated.In(time.FixedZone("MSK", 3*3600))
+	after.Items[0], after.Items[1] = after.Items[1], after.Items[0]
+	after.Items[0].Price = 25
+
+	changes := Diff(before, after)
+	if strings.Join(changes, ",") != "payment.amount,items[0].price" {
+		t.Errorf("Unexpected changes: %v", changes)
+	}
+	if changes := Diff(before, before); len(changes) != 0 {
+		t.Errorf("Expected no changes for identical records, got %v", changes)
+	}
+}
+
+func newReplayer(apply bool, stored map[string]db.OrderRecord, out *bytes.Buffer) (*Replayer, *[]string) {
+	var inserted []string
+	decode := func(d ingest.Delivery) (db.OrderRecord, error) {
+		var r struct {
+			UID    string `json:"uid"`
+			Amount int    `json:"amount"`
+		}
+		if err := json.Unmarshal(d.Data, &r); err != nil || r.UID == "" {
+			return db.OrderRecord{}, errors.New("invalid order")
+		}
+		return record(r.UID, r.Amount), nil
+	}
+	lookup := func(ctx context.Context, uids []string) ([]db.OrderRecord, error) {
+		var records []db.OrderRecord
+		for _, uid := range uids {
+			if r, ok := stored[uid]; ok {
+				records = append(records, r)
+			}
+		}
+		return records, nil
+	}
+	store := func(ctx context.Context, records []db.OrderRecord) ([]error, error) {
+		errs := make([]error, len(records))
+		for i, r := range records {
+			if r.Order.OrderUID == "broken" {
+				errs[i] = errors.New("violates constraint")
+				continue
+			}
+			inserted = append(inserted, r.Order.OrderUID)
+		}
+		return errs, nil
+	}
+	return New(decode, lookup, store, Options{Apply: apply, BatchSize: 2, Entries: out}), &inserted
+}
+
+func replayAll(t *testing.T, r *Replayer, messages ...string) Report {
+	t.Helper()
+	for i, m := range messages {
+		if err := r.Handle(context.Background(), ingest.Delivery{ID: string(rune('1' + i)), Data: []byte(m)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err := r.Finish(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+var replayMessages = []string{
+	`{"uid":"existing","amount":100}`,
+	`{"uid":"changed","amount":999}`,
+	`{"uid":"new","amount":1}`,
+	`not json`,
+	`{"uid":"new","amount":1}`,
+	`{"uid":"broken","amount":1}`,
+}
+
+func TestReplayerDryRun(t *testing.T) {
+	stored := map[string]db.OrderRecord{"existing": record("existing", 100), "changed": record("changed", 100)}
+	var out bytes.Buffer
+	r, inserted := newReplayer(false, stored, &out)
+
+	report := replayAll(t, r, replayMessages...)
+	expected := Report{Messages: 6, Insert: 2, Unchanged: 2, Changed: 1, Rejected: 1}
+	if report != expected {
+		t.Errorf("Expected report %+v, got %+v", expected, report)
+	}
+	if len(*inserted) != 0 {
+		t.Errorf("Expected a dry run not to store anything, got %v", *inserted)
+	}
+
+	var entries []Entry
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 6 || entries[1].Outcome != OutcomeChanged || strings.Join(entries[1].Changes, ",") != "payment.amount" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestReplayerApply(t *testing.T) {
+	stored := map[string]db.OrderRecord{"existing": record("existing", 100)}
+	var out bytes.Buffer
+	r, inserted := newReplayer(true, stored, &out)
+
+	report := replayAll(t, r, replayMessages...)
+	if report.Applied != 2 || report.Failed != 1 || report.Insert != 2 || report.Unchanged != 2 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if strings.Join(*inserted, ",") != "changed,new" {
+		t.Errorf("Expected missing orders to be stored once, got %v", *inserted)
+	}
+}
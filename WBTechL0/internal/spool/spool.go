@@ -0,0 +1,207 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/importer"
+	"WBTechL0/internal/ingest"
+)
+
+const (
+	spoolPattern   = "*.ndjson"
+	positionSuffix = ".pos"
+)
+
+var ErrNotStarted = errors.New("spool source is not running")
+
+type Source struct {
+	dir       string
+	poll      time.Duration
+	redeliver *ingest.Redeliverer
+	running   atomic.Bool
+	mu        sync.Mutex
+	files     map[string]*spoolFile
+}
+
+type spoolFile struct {
+	path    string
+	read    int64
+	tracker ingest.CommitTracker
+	saveMu  sync.Mutex
+}
+
+type position struct {
+	file *spoolFile
+	end  int64
+}
+
+func NewSource(cfg config.SpoolConfig, retryDelay time.Duration) *Source {
+	return &Source{
+		dir:       cfg.Dir,
+		poll:      cfg.PollInterval,
+		redeliver: ingest.NewRedeliverer(retryDelay),
+		files:     make(map[string]*spoolFile),
+	}
+}
+
+func (s *Source) Start(ctx context.Context, handle func(ingest.Delivery)) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	s.redeliver.Bind(ctx, handle)
+	defer s.redeliver.Stop()
+	s.running.Store(true)
+	defer s.running.Store(false)
+
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+	for {
+		if err := s.scan(ctx, handle); err != nil {
+			log.Println("Failed to scan spool directory:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Source) scan(ctx context.Context, handle func(ingest.Delivery)) error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, spoolPattern))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	s.prune(paths)
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return nil
+		}
+		f, err := s.file(path)
+		if err != nil {
+			log.Printf("Skipping spool file %s: %v\n", path, err)
+			continue
+		}
+		if err := s.tail(ctx, f, handle); err != nil {
+			log.Printf("Failed to read spool file %s: %v\n", path, err)
+		}
+	}
+	return nil
+}
+
+func (s *Source) prune(paths []string) {
+	present := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		present[path] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for path := range s.files {
+		if !present[path] {
+			delete(s.files, path)
+		}
+	}
+}
+
+func (s *Source) file(path string) (*spoolFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files[path]; ok {
+		return f, nil
+	}
+	committed, err := importer.LoadCheckpoint(path + positionSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("reading position: %w", err)
+	}
+	f := &spoolFile{path: path, read: int64(committed)}
+	s.files[path] = f
+	return f, nil
+}
+
+func (s *Source) tail(ctx context.Context, f *spoolFile, handle func(ingest.Delivery)) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Seek(f.read, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	name := filepath.Base(f.path)
+	for ctx.Err() == nil {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start := f.read
+		f.read += int64(len(line))
+		pos := position{file: f, end: f.read}
+		f.tracker.Add(pos.end)
+
+		data := bytes.TrimSpace(line)
+		if len(data) == 0 {
+			s.commit(pos)
+			continue
+		}
+		handle(ingest.Delivery{
+			ID:   fmt.Sprintf("%s@%d", name, start),
+			Data: data,
+			Ref:  pos,
+		})
+	}
+	return nil
+}
+
+func (s *Source) Ack(d ingest.Delivery) error {
+	return s.commit(d.Ref.(position))
+}
+
+func (s *Source) Nack(d ingest.Delivery, retry bool) error {
+	if retry {
+		s.redeliver.Redeliver(d)
+		return nil
+	}
+	log.Println("Skipping poison spool record:", d.ID)
+	return s.commit(d.Ref.(position))
+}
+
+func (s *Source) commit(pos position) error {
+	pos.file.saveMu.Lock()
+	defer pos.file.saveMu.Unlock()
+	offset, ok := pos.file.tracker.Done(pos.end)
+	if !ok {
+		return nil
+	}
+	return importer.SaveCheckpoint(pos.file.path+positionSuffix, int(offset))
+}
+
+func (s *Source) Ready(ctx context.Context) error {
+	if !s.running.Load() {
+		return ErrNotStarted
+	}
+	return nil
+}
+
+func (s *Source) Reconnects() uint64 {
+	return 0
+}
@@ -0,0 +1,139 @@
+package spool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/ingest"
+)
+
+func startSource(t *testing.T, dir string, handle func(*Source, ingest.Delivery)) (*Source, context.CancelFunc) {
+	t.Helper()
+	source := NewSource(config.SpoolConfig{Dir: dir, PollInterval: 5 * time.Millisecond}, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	go source.Start(ctx, func(d ingest.Delivery) { handle(source, d) })
+	return source, cancel
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSourceTailsSpoolFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "0001.ndjson")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n\n{\"n\":2}\n{\"n\":3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(chan string, 10)
+	_, cancel := startSource(t, dir, func(s *Source, d ingest.Delivery) {
+		seen <- string(d.Data)
+		s.Ack(d)
+	})
+
+	var got []string
+	waitFor(t, func() bool {
+		select {
+		case data := <-seen:
+			got = append(got, data)
+		default:
+		}
+		return len(got) == 2
+	})
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("}\n")
+	f.Close()
+
+	waitFor(t, func() bool {
+		select {
+		case data := <-seen:
+			got = append(got, data)
+		default:
+		}
+		return len(got) == 3
+	})
+	cancel()
+
+	if strings.Join(got, ",") != `{"n":1},{"n":2},{"n":3}` {
+		t.Errorf("Unexpected records: %v", got)
+	}
+	waitFor(t, func() bool {
+		pos, _ := os.ReadFile(path + positionSuffix)
+		return strings.TrimSpace(string(pos)) == "25"
+	})
+}
+
+func TestSourceResumesFromCommittedPosition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "0001.ndjson")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	delivered := make(chan ingest.Delivery, 10)
+	source, cancel := startSource(t, dir, func(s *Source, d ingest.Delivery) { delivered <- d })
+	first, second := <-delivered, <-delivered
+	<-delivered
+	cancel()
+
+	if err := source.Ack(second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + positionSuffix); !os.IsNotExist(err) {
+		t.Fatal("Expected no position to be saved while the first record is pending")
+	}
+	if err := source.Nack(first, false); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := make(chan string, 10)
+	_, cancel = startSource(t, dir, func(s *Source, d ingest.Delivery) { resumed <- string(d.Data) })
+	defer cancel()
+	select {
+	case data := <-resumed:
+		if data != `{"n":3}` {
+			t.Errorf("Expected to resume at the third record, got %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for resumed delivery")
+	}
+}
+
+func TestSourceForgetsRemovedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "0001.ndjson")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	delivered := make(chan ingest.Delivery, 10)
+	source, cancel := startSource(t, dir, func(s *Source, d ingest.Delivery) { delivered <- d })
+	defer cancel()
+	source.Ack(<-delivered)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		source.mu.Lock()
+		defer source.mu.Unlock()
+		return len(source.files) == 0
+	})
+}
@@ -0,0 +1,11 @@
+-- Existing deployments stored date_created as TIMESTAMP holding UTC wall-clock time.
+-- Databases created from init.sql already use TIMESTAMPTZ and are left untouched.
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'orders' AND column_name = 'date_created') = 'timestamp without time zone' THEN
+        ALTER TABLE orders
+            ALTER COLUMN date_created TYPE TIMESTAMPTZ
+            USING date_created AT TIME ZONE 'UTC';
+    END IF;
+END $$;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    rate_date DATE NOT NULL,
+    base CHAR(3) NOT NULL,
+    quote CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (rate_date, base, quote)
+);
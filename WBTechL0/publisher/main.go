@@ -1,77 +1,36 @@
 package main
 
 import (
-	"encoding/json"
-	"github.com/nats-io/stan.go"
+	"context"
+	"flag"
+	"fmt"
 	"log"
-)
+	"time"
 
-type Delivery struct {
-	Name    string `json:"name"`
-	Phone   string `json:"phone"`
-	Zip     string `json:"zip"`
-	City    string `json:"city"`
-	Address string `json:"address"`
-	Region  string `json:"region"`
-	Email   string `json:"email"`
-}
-
-type Payment struct {
-	Transaction  string `json:"transaction"`
-	RequestID    string `json:"request_id"`
-	Currency     string `json:"currency"`
-	Provider     string `json:"provider"`
-	Amount       int    `json:"amount"`
-	PaymentDT    int    `json:"payment_dt"`
-	Bank         string `json:"bank"`
-	DeliveryCost int    `json:"delivery_cost"`
-	GoodsTotal   int    `json:"goods_total"`
-	CustomFee    int    `json:"custom_fee"`
-}
-
-type Item struct {
-	ChrtID      int    `json:"chrt_id"`
-	TrackNumber string `json:"track_number"`
-	Price       int    `json:"price"`
-	RID         string `json:"rid"`
-	Name        string `json:"name"`
-	Sale        int    `json:"sale"`
-	Size        string `json:"size"`
-	TotalPrice  int    `json:"total_price"`
-	NMID        int    `json:"nm_id"`
-	Brand       string `json:"brand"`
-	Status      int    `json:"status"`
-}
-
-type Order struct {
-	OrderUID          string   `json:"order_uid"`
-	TrackNumber       string   `json:"track_number"`
-	Entry             string   `json:"entry"`
-	Delivery          Delivery `json:"delivery"`
-	Payment           Payment  `json:"payment"`
-	Items             []Item   `json:"items"`
-	Locale            string   `json:"locale"`
-	InternalSignature string   `json:"internal_signature"`
-	CustomerID        string   `json:"customer_id"`
-	DeliveryService   string   `json:"delivery_service"`
-	Shardkey          string   `json:"shardkey"`
-	SmID              int      `json:"sm_id"`
-	DateCreated       string   `json:"date_created"`
-	OofShard          string   `json:"oof_shard"`
-}
+	"WBTechL0/internal/message"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/stan.go"
+)
 
 func main() {
-	sc, err := stan.Connect("test-cluster", "publisher")
+	backend := flag.String("backend", "stan", "messaging backend: stan or jetstream")
+	url := flag.String("url", nats.DefaultURL, "NATS server URL")
+	clusterID := flag.String("cluster", "test-cluster", "NATS Streaming cluster ID")
+	subject := flag.String("subject", "orders", "subject to publish to")
+	formatName := flag.String("format", "json", "payload format: json, protobuf or msgpack")
+	flag.Parse()
+
+	format, err := parseFormat(*formatName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer sc.Close()
 
-	order := Order{
+	order := message.Order{
 		OrderUID:    "b563feb7b2b84b6test",
 		TrackNumber: "WBILMTESTTRACK",
 		Entry:       "WBIL",
-		Delivery: Delivery{
+		Delivery: message.Delivery{
 			Name:    "Test Testov",
 			Phone:   "+9720000000",
 			Zip:     "2639809",
@@ -80,19 +39,19 @@ func main() {
 			Region:  "Kraiot",
 			Email:   "test@gmail.com",
 		},
-		Payment: Payment{
+		Payment: message.Payment{
 			Transaction:  "b563feb7b2b84b6test",
 			RequestID:    "",
 			Currency:     "USD",
 			Provider:     "wbpay",
 			Amount:       1817,
-			PaymentDT:    1637907727,
+			PaymentDt:    1637907727,
 			Bank:         "alpha",
 			DeliveryCost: 1500,
 			GoodsTotal:   317,
 			CustomFee:    0,
 		},
-		Items: []Item{
+		Items: []message.Item{
 			{
 				ChrtID:      9934930,
 				TrackNumber: "WBILMTESTTRACK",
@@ -111,21 +70,77 @@ func main() {
 		InternalSignature: "",
 		CustomerID:        "test",
 		DeliveryService:   "meest",
-		Shardkey:          "9",
-		SmID:              99,
+		ShardKey:          "9",
+		SMID:              99,
 		DateCreated:       "2021-11-26T06:22:19Z",
-		OofShard:          "1",
+		OOFShard:          "1",
 	}
 
-	data, err := json.Marshal(order)
+	data, err := message.EncodeAs(format, order)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = sc.Publish("orders", data)
+	switch *backend {
+	case "stan":
+		err = publishSTAN(*url, *clusterID, *subject, format, data)
+	case "jetstream":
+		err = publishJetStream(*url, *subject, format, data)
+	default:
+		log.Fatalf("Unknown backend %q", *backend)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Println("Published message to NATS")
+	log.Printf("Published %s message to NATS\n", format)
+}
+
+func parseFormat(name string) (message.Format, error) {
+	for _, format := range []message.Format{message.FormatJSON, message.FormatProtobuf, message.FormatMsgPack} {
+		if format.String() == name {
+			return format, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown format %q", name)
+}
+
+func publishSTAN(url, clusterID, subject string, format message.Format, data []byte) error {
+	data, err := message.Wrap(format, data)
+	if err != nil {
+		return err
+	}
+
+	sc, err := stan.Connect(clusterID, "publisher", stan.NatsURL(url))
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+
+	return sc.Publish(subject, data)
+}
+
+func publishJetStream(url, subject string, format message.Format, data []byte) error {
+	nc, err := nats.Connect(url, nats.Name("publisher"))
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg := nats.NewMsg(subject)
+	msg.Header.Set("Content-Type", format.ContentType())
+	msg.Data = data
+	ack, err := js.PublishMsg(ctx, msg)
+	if err != nil {
+		return err
+	}
+	log.Printf("Stored in stream %s at sequence %d\n", ack.Stream, ack.Sequence)
+	return nil
 }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"WBTechL0/internal/config"
+	"WBTechL0/internal/db"
+	"WBTechL0/internal/ingest"
+	"WBTechL0/internal/nats"
+	"WBTechL0/internal/replay"
+)
+
+func runReplay(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fromSeq := fs.Uint64("from-seq", 0, "replay starting at this stream sequence number")
+	fromTime := fs.String("from-time", "", "replay starting at this RFC3339 time, or this long ago (e.g. 6h)")
+	apply := fs.Bool("apply", false, "store orders that are missing from the database (default: dry run)")
+	reportPath := fs.String("report", "-", "file receiving one NDJSON entry per replayed message (- for stdout)")
+	idle := fs.Duration("idle", 5*time.Second, "stop once no message has arrived for this long")
+	limit := fs.Int("limit", 0, "stop after this many messages (0 for no limit)")
+	batchSize := fs.Int("batch-size", replay.DefaultBatchSize, "number of messages compared against the database at once")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: main replay (-from-seq N | -from-time T) [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	start := nats.ReplayStart{Sequence: *fromSeq}
+	if *fromTime != "" {
+		t, err := parseReplayTime(*fromTime, time.Now())
+		if err != nil {
+			return err
+		}
+		start.Time = t
+	}
+	source, err := nats.NewReplaySource(cfg.NATS, start)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *reportPath != "-" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	dbConn, err := openDB(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	lookup := func(ctx context.Context, orderUIDs []string) ([]db.OrderRecord, error) {
+		return db.GetOrderRecordsByUIDs(ctx, dbConn, orderUIDs)
+	}
+	replayer := replay.New(ingest.DecodeRecord, lookup, ingest.CopyStore(dbConn), replay.Options{
+		Apply:     *apply,
+		BatchSize: *batchSize,
+		Entries:   w,
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	activity := make(chan struct{}, 1)
+	go stopWhenIdle(ctx, cancel, activity, *idle)
+
+	var mu sync.Mutex
+	var handleErr error
+	seen := 0
+	err = source.Start(ctx, func(d ingest.Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+
+		if err := replayer.Handle(ctx, d); err != nil {
+			handleErr = err
+			cancel()
+			return
+		}
+		if err := source.Ack(d); err != nil {
+			log.Printf("Failed to acknowledge replayed message %s: %v\n", d.ID, err)
+		}
+		seen++
+		if *limit > 0 && seen >= *limit {
+			cancel()
+		}
+	})
+
+	mu.Lock()
+	err = errors.Join(err, handleErr)
+	mu.Unlock()
+	report, finishErr := replayer.Finish(context.WithoutCancel(ctx))
+	mode := "dry run"
+	if *apply {
+		mode = "apply"
+	}
+	log.Printf("Replay finished (%s): messages=%d insert=%d unchanged=%d changed=%d rejected=%d failed=%d applied=%d\n",
+		mode, report.Messages, report.Insert, report.Unchanged, report.Changed, report.Rejected, report.Failed, report.Applied)
+	if report.Changed > 0 {
+		log.Println("Changed orders are reported only; ingestion never overwrites stored orders")
+	}
+	return errors.Join(err, finishErr)
+}
+
+func stopWhenIdle(ctx context.Context, cancel context.CancelFunc, activity <-chan struct{}, idle time.Duration) {
+	timer := time.NewTimer(idle)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-activity:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(idle)
+		case <-timer.C:
+			cancel()
+			return
+		}
+	}
+}
+
+func parseReplayTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	ago, err := time.ParseDuration(value)
+	if err != nil || ago <= 0 {
+		return time.Time{}, fmt.Errorf("invalid -from-time %q: expected an RFC3339 time or a positive duration", value)
+	}
+	return now.Add(-ago), nil
+}